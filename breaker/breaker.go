@@ -0,0 +1,277 @@
+// Package breaker trips circuit breakers when the errors of a template family
+// exceed a failure rate over a sliding window. Errors are matched with
+// IsRelatedTo, so the templates categorizing the failures of a dependency drive
+// the breaker of that dependency.
+//
+//	payments := breaker.New("payments", breaker.Config{Family: PaymentsError})
+//
+//	err := payments.Do(func() error {
+//		return client.Charge(ctx, order)
+//	})
+//
+// While the circuit is open, calls fail with an error created from the
+// CircuitOpen template, carrying the dependency name and the time to wait
+// before retrying.
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+// State of a circuit breaker
+type State int
+
+const (
+	// Calls are allowed and their outcome is recorded
+	StateClosed State = iota
+	// Calls are rejected until the open duration elapsed
+	StateOpen
+	// A limited number of probe calls are allowed to decide whether to close the circuit
+	StateHalfOpen
+)
+
+// Returns the name of the state
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Template of the errors returned while a circuit is open
+var CircuitOpen = khata.NewTemplate().
+	SetType("CircuitOpen").
+	SetCode(503).
+	SetNonFatal().
+	SetMessage("circuit open")
+
+// Name of the dependency whose circuit is open
+var DependencyKey = khata.NewKey[string]("dependency")
+
+// Time to wait before the circuit accepts calls again. It is zero when a
+// half-open circuit is rejecting calls while its probes are in flight.
+var RetryAfterKey = khata.NewKey[time.Duration]("retryAfter")
+
+// Config of a circuit breaker. Zero values are replaced by the defaults.
+type Config struct {
+	// Errors related to this template count as failures. Required.
+	Family *khata.KhataTemplate
+	// Duration of the sliding window. Defaults to 1 minute.
+	Window time.Duration
+	// Number of buckets of the sliding window. Defaults to 10, and is limited
+	// to the duration of the window in nanoseconds.
+	Buckets int
+	// Minimum number of failures in the window before the circuit can trip. Defaults to 5.
+	MinFailures int
+	// Ratio of failures over the calls of the window tripping the circuit. Defaults to 0.5.
+	FailureRate float64
+	// Duration the circuit stays open before probing the dependency. Defaults to 30 seconds.
+	OpenDuration time.Duration
+	// Number of successful probes closing a half-open circuit. Defaults to 1.
+	HalfOpenProbes int
+	// Clock used to measure time. Defaults to the clock of the family template, or the system clock.
+	Clock khata.Clock
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.Buckets <= 0 {
+		c.Buckets = 10
+	}
+	// Buckets are at least one nanosecond wide
+	if time.Duration(c.Buckets) > c.Window {
+		c.Buckets = int(c.Window)
+	}
+	if c.MinFailures <= 0 {
+		c.MinFailures = 5
+	}
+	if c.FailureRate <= 0 {
+		c.FailureRate = 0.5
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 30 * time.Second
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+	if c.Clock == nil && c.Family != nil {
+		c.Clock = c.Family.Clock()
+	}
+	if c.Clock == nil {
+		c.Clock = khata.SystemClock
+	}
+	return c
+}
+
+// Breaker is the circuit breaker of a dependency. It is safe for concurrent use.
+type Breaker struct {
+	name   string
+	config Config
+
+	mutex          sync.Mutex
+	state          State
+	window         *window
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+}
+
+// Create a closed circuit breaker for the named dependency. Panics if the config has no family.
+func New(name string, config Config) *Breaker {
+	if config.Family == nil {
+		panic("breaker: the config of " + name + " has no family template")
+	}
+
+	config = config.withDefaults()
+
+	return &Breaker{
+		name:   name,
+		config: config,
+		state:  StateClosed,
+		window: newWindow(config.Window, config.Buckets),
+	}
+}
+
+// Returns the name of the dependency
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Returns the family template of the breaker
+func (b *Breaker) Family() *khata.KhataTemplate {
+	return b.config.Family
+}
+
+// Returns the current state of the circuit
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.advance(b.config.Clock.Now())
+	return b.state
+}
+
+// Returns true if the error, or one of its causes, belongs to the family of the breaker
+func (b *Breaker) Matches(err error) bool {
+	return khata.MatchError(khata.Template(b.config.Family), err) != nil
+}
+
+// Returns nil if a call is allowed, or a CircuitOpen error if the circuit rejects it.
+// In the half-open state, allowed calls are probes and their outcome must be recorded.
+func (b *Breaker) Allow() *khata.Khata {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.config.Clock.Now()
+	b.advance(now)
+
+	switch b.state {
+	case StateOpen:
+		return b.openError(b.openedAt.Add(b.config.OpenDuration).Sub(now))
+	case StateHalfOpen:
+		if b.probesInFlight+b.probeSuccesses >= b.config.HalfOpenProbes {
+			return b.openError(0)
+		}
+		b.probesInFlight++
+	}
+
+	return nil
+}
+
+// Record the outcome of a call. Nil errors and errors outside of the family of
+// the breaker are successes, the others are failures. In the half-open state,
+// successes only count for the calls allowed as probes.
+func (b *Breaker) Record(err error) {
+	b.record(err, true)
+}
+
+// Record the outcome of a call, that may be a probe allowed by Allow when
+// admitted is true. Outcomes observed elsewhere, like the errors routed by a
+// Group, never complete a probe.
+func (b *Breaker) record(err error, admitted bool) {
+	failure := err != nil && b.Matches(err)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.config.Clock.Now()
+	b.advance(now)
+
+	switch b.state {
+	case StateClosed:
+		b.window.record(now, failure)
+		b.tripIfNeeded(now)
+	case StateHalfOpen:
+		probe := admitted && b.probesInFlight > 0
+		if probe {
+			b.probesInFlight--
+		}
+		if failure {
+			b.open(now)
+		} else if probe {
+			if b.probeSuccesses++; b.probeSuccesses >= b.config.HalfOpenProbes {
+				b.close()
+			}
+		}
+	}
+}
+
+// Run the function if the circuit allows it and record its outcome.
+// Returns the CircuitOpen error without calling the function if the circuit rejects the call.
+func (b *Breaker) Do(fn func() error) error {
+	if k := b.Allow(); k != nil {
+		return k
+	}
+
+	err := fn()
+	b.Record(err)
+	return err
+}
+
+// Move an open circuit to the half-open state once the open duration elapsed
+func (b *Breaker) advance(now time.Time) {
+	if b.state == StateOpen && !now.Before(b.openedAt.Add(b.config.OpenDuration)) {
+		b.state = StateHalfOpen
+		b.probesInFlight = 0
+		b.probeSuccesses = 0
+	}
+}
+
+func (b *Breaker) tripIfNeeded(now time.Time) {
+	successes, failures := b.window.counts(now)
+
+	if failures < b.config.MinFailures {
+		return
+	}
+
+	if float64(failures)/float64(successes+failures) >= b.config.FailureRate {
+		b.open(now)
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+	b.window.reset()
+}
+
+func (b *Breaker) close() {
+	b.state = StateClosed
+	b.window.reset()
+}
+
+func (b *Breaker) openError(retryAfter time.Duration) *khata.Khata {
+	k := CircuitOpen.New()
+	DependencyKey.Set(k, b.name)
+	RetryAfterKey.Set(k, retryAfter)
+	return k
+}
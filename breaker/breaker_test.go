@@ -0,0 +1,226 @@
+package breaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/breaker"
+)
+
+type testClock struct {
+	current time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.current
+}
+
+func newTestBreaker() (*breaker.Breaker, *khata.KhataTemplate, *testClock) {
+	clock := &testClock{current: time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)}
+	payments := khata.NewTemplate().SetType("Payments")
+
+	b := breaker.New("payments", breaker.Config{
+		Family:       payments,
+		Window:       10 * time.Second,
+		MinFailures:  2,
+		FailureRate:  0.5,
+		OpenDuration: 5 * time.Second,
+		Clock:        clock,
+	})
+
+	return b, payments, clock
+}
+
+func TestBreakerTrips(t *testing.T) {
+	b, payments, clock := newTestBreaker()
+	timeout := payments.Extend().SetCode(504)
+
+	b.Record(nil)
+	b.Record(errors.New("This is an unrelated error"))
+	b.Record(timeout.New())
+
+	if b.State() != breaker.StateClosed {
+		t.Error("Record() tripped the circuit before reaching the minimum number of failures")
+		return
+	}
+
+	b.Record(khata.Wrap(timeout.New()))
+
+	if b.State() != breaker.StateOpen {
+		t.Error("Record() did not trip the circuit when the failure rate was exceeded")
+		return
+	}
+
+	clock.current = clock.current.Add(2 * time.Second)
+	k := b.Allow()
+
+	if k == nil || !k.IsInstanceOf(breaker.CircuitOpen) {
+		t.Error("Allow() did not reject the call while the circuit was open")
+		return
+	}
+
+	if breaker.RetryAfterKey.GetOr(k, 0) != 3*time.Second || breaker.DependencyKey.GetOr(k, "") != "payments" {
+		t.Error("Allow() did not set the retry-after metadata")
+		return
+	}
+}
+
+func TestBreakerSlidingWindow(t *testing.T) {
+	b, payments, clock := newTestBreaker()
+
+	b.Record(payments.New())
+	clock.current = clock.current.Add(11 * time.Second)
+	b.Record(payments.New())
+
+	if b.State() != breaker.StateClosed {
+		t.Error("Record() counted failures that left the window")
+		return
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b, payments, clock := newTestBreaker()
+
+	b.Record(payments.New())
+	b.Record(payments.New())
+	clock.current = clock.current.Add(5 * time.Second)
+
+	if b.State() != breaker.StateHalfOpen {
+		t.Error("State() did not move to half-open after the open duration")
+		return
+	}
+
+	b.Record(nil)
+
+	if b.State() != breaker.StateHalfOpen {
+		t.Error("Record() closed the circuit with the success of a call that was not a probe")
+		return
+	}
+
+	if b.Allow() != nil {
+		t.Error("Allow() rejected the probe of a half-open circuit")
+		return
+	}
+
+	if k := b.Allow(); k == nil || breaker.RetryAfterKey.GetOr(k, -1) != 0 {
+		t.Error("Allow() did not reject the calls while the probe was in flight")
+		return
+	}
+
+	b.Record(payments.New())
+
+	if b.State() != breaker.StateOpen {
+		t.Error("Record() did not reopen the circuit after a failed probe")
+		return
+	}
+
+	clock.current = clock.current.Add(5 * time.Second)
+	err := b.Do(func() error { return nil })
+
+	if err != nil || b.State() != breaker.StateClosed {
+		t.Error("Do() did not close the circuit after a successful probe")
+		return
+	}
+}
+
+func TestBreakerShortWindow(t *testing.T) {
+	clock := &testClock{current: time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)}
+	payments := khata.NewTemplate().SetType("Payments")
+
+	b := breaker.New("payments", breaker.Config{
+		Family:      payments,
+		Window:      5 * time.Nanosecond,
+		Buckets:     10,
+		MinFailures: 2,
+		Clock:       clock,
+	})
+
+	b.Record(payments.New())
+	b.Record(payments.New())
+
+	if b.State() != breaker.StateOpen {
+		t.Error("Record() did not trip a circuit whose window is shorter than its number of buckets")
+		return
+	}
+}
+
+func TestBreakerTimesBefore1970(t *testing.T) {
+	payments := khata.NewTemplate().SetType("Payments")
+
+	b := breaker.New("payments", breaker.Config{
+		Family:      payments,
+		MinFailures: 2,
+		Clock:       khata.ClockFunc(func() time.Time { return time.Time{} }),
+	})
+
+	b.Record(payments.New())
+	b.Record(payments.New())
+
+	if b.State() != breaker.StateOpen {
+		t.Error("Record() did not count the failures with a zero clock")
+		return
+	}
+
+	clock := &testClock{current: time.Date(1969, 12, 31, 23, 59, 50, 0, time.UTC)}
+
+	b = breaker.New("payments", breaker.Config{
+		Family:      payments,
+		Window:      10 * time.Second,
+		MinFailures: 2,
+		Clock:       clock,
+	})
+
+	b.Record(payments.New())
+	clock.current = clock.current.Add(11 * time.Second)
+	b.Record(payments.New())
+
+	if b.State() != breaker.StateClosed {
+		t.Error("Record() did not expire the failures recorded before 1970")
+		return
+	}
+}
+
+func TestGroup(t *testing.T) {
+	b, payments, _ := newTestBreaker()
+	group := breaker.NewGroup(b)
+	defer khata.AddObserver(group)()
+
+	payments.New().MarkHandled()
+	khata.New("This is an unrelated error").MarkHandled()
+	payments.New().MarkHandled()
+
+	if group.Breaker("payments").State() != breaker.StateOpen {
+		t.Error("Group did not route the handled errors to the breaker")
+		return
+	}
+}
+
+func TestGroupHalfOpen(t *testing.T) {
+	b, payments, clock := newTestBreaker()
+	group := breaker.NewGroup(b)
+
+	group.Record(payments.New())
+	group.Record(payments.New())
+	clock.current = clock.current.Add(5 * time.Second)
+
+	if b.Allow() != nil {
+		t.Error("Allow() rejected the probe of a half-open circuit")
+		return
+	}
+
+	group.Record(payments.New())
+
+	if b.State() != breaker.StateOpen {
+		t.Error("Group did not reopen the circuit after a failure")
+		return
+	}
+
+	clock.current = clock.current.Add(5 * time.Second)
+
+	if b.Allow() != nil || b.Allow() == nil {
+		t.Error("Allow() did not admit a single probe after the circuit reopened")
+		return
+	}
+}
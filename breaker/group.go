@@ -0,0 +1,57 @@
+package breaker
+
+import "github.com/cmseguin/khata"
+
+// Group routes failures to the breakers of their dependencies. It is a
+// khata.Observer, so it can consume the errors handled by the program:
+//
+//	group := breaker.NewGroup(payments, inventory)
+//	khata.AddObserver(group)
+//
+// Only failures are known from the errors, so the breakers of a group trip
+// on the number of failures in their window unless successes are recorded
+// on the breakers themselves.
+type Group struct {
+	breakers []*Breaker
+}
+
+// Create a group of breakers
+func NewGroup(breakers ...*Breaker) *Group {
+	return &Group{breakers: breakers}
+}
+
+// Returns the breaker of the named dependency, or nil
+func (g *Group) Breaker(name string) *Breaker {
+	for _, b := range g.breakers {
+		if b.name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Returns the breakers whose family the error belongs to
+func (g *Group) Match(err error) []*Breaker {
+	matches := []*Breaker{}
+	for _, b := range g.breakers {
+		if b.Matches(err) {
+			matches = append(matches, b)
+		}
+	}
+	return matches
+}
+
+// Record the error as a failure of every breaker whose family it belongs to.
+// The error was not necessarily returned by a call allowed as a probe, so it
+// does not complete the probes of half-open breakers.
+func (g *Group) Record(err error) {
+	for _, b := range g.Match(err) {
+		b.record(err, false)
+	}
+}
+
+func (g *Group) ErrorCreated(k *khata.Khata) {}
+
+func (g *Group) ErrorHandled(k *khata.Khata) {
+	g.Record(k)
+}
@@ -0,0 +1,86 @@
+package breaker
+
+import "time"
+
+type bucket struct {
+	index     int64
+	successes int
+	failures  int
+}
+
+// window counts the outcomes of the calls over a sliding time window,
+// divided in buckets that expire one at a time. Buckets are numbered from the
+// first observation, so any time works, including the zero time.
+type window struct {
+	width   time.Duration
+	buckets []bucket
+	origin  time.Time
+	started bool
+}
+
+func newWindow(duration time.Duration, buckets int) *window {
+	return &window{
+		width:   duration / time.Duration(buckets),
+		buckets: make([]bucket, buckets),
+	}
+}
+
+// Returns the number of the bucket of the given time, which is negative for
+// the times before the first observation
+func (w *window) index(t time.Time) int64 {
+	if !w.started {
+		w.origin = t
+		w.started = true
+	}
+
+	elapsed := t.Sub(w.origin)
+	index := int64(elapsed / w.width)
+	if elapsed < 0 && elapsed%w.width != 0 {
+		index--
+	}
+
+	return index
+}
+
+// Returns the bucket of the given time, resetting it if it expired
+func (w *window) bucket(t time.Time) *bucket {
+	index := w.index(t)
+	n := int64(len(w.buckets))
+	b := &w.buckets[((index%n)+n)%n]
+
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+
+	return b
+}
+
+func (w *window) record(t time.Time, failure bool) {
+	b := w.bucket(t)
+
+	if failure {
+		b.failures++
+	} else {
+		b.successes++
+	}
+}
+
+// Returns the number of successes and failures recorded in the window ending at the given time
+func (w *window) counts(t time.Time) (successes int, failures int) {
+	index := w.index(t)
+
+	for _, b := range w.buckets {
+		if b.index > index-int64(len(w.buckets)) && b.index <= index {
+			successes += b.successes
+			failures += b.failures
+		}
+	}
+
+	return successes, failures
+}
+
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
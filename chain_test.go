@@ -0,0 +1,61 @@
+package khata_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestKhataWrapChain(t *testing.T) {
+	base := errors.New("connection refused")
+	repositoryError := khata.NewTemplate().SetType("Repository")
+	serviceError := khata.NewTemplate().SetType("Service")
+
+	inner := repositoryError.Wrap(base).Explain("This is an explanation of the repository").SetProperty("table", "users")
+	middle := khata.Wrap(fmt.Errorf("loading user: %w", inner))
+	outer := serviceError.Wrap(middle).Explain("This is an explanation of the service")
+
+	if outer.Cause() != middle || middle.Cause() != inner || inner.Cause() != nil {
+		t.Error("Cause() did not return the wrapped khata error")
+		return
+	}
+
+	chain := outer.Chain()
+
+	if len(chain) != 3 || chain[0] != outer || chain[2] != inner {
+		t.Error("Chain() did not return every layer")
+		return
+	}
+
+	if outer.RootCause() != inner || inner.RootCause() != inner {
+		t.Error("RootCause() did not return the innermost khata error")
+		return
+	}
+
+	if !errors.Is(outer, base) {
+		t.Error("errors.Is() did not look through the wrap chain")
+		return
+	}
+
+	var output struct {
+		Cause struct {
+			Cause struct {
+				ErrorType  string                 `json:"errorType"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"cause"`
+		} `json:"cause"`
+	}
+
+	if err := json.Unmarshal([]byte(outer.ToJSON()), &output); err != nil {
+		t.Error("ToJSON() did not return valid JSON")
+		return
+	}
+
+	if output.Cause.Cause.ErrorType != "Repository" || output.Cause.Cause.Properties["table"] != "users" {
+		t.Error("ToJSON() did not nest the causes")
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package khata
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to khata errors. It can be replaced to make
+// the timestamps of errors reproducible.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc allows to use a function as a Clock
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// The clock used by default, reading the system time
+var SystemClock Clock = ClockFunc(time.Now)
+
+// StackCapture collects the stack traces of errors and the locations of explanations.
+// It can be replaced to make traces reproducible.
+type StackCapture interface {
+	// Returns the trace of the current goroutine, without the khata frames
+	Trace() []KhataTrace
+	// Returns the location of the code calling an explanation method
+	Caller() KhataTrace
+}
+
+var (
+	settingsMutex sync.RWMutex
+	clock         = SystemClock
+	stackCapture  StackCapture
+)
+
+// Set the clock used by errors that do not get one from their template. A nil clock restores the SystemClock.
+func SetClock(c Clock) {
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	if c == nil {
+		c = SystemClock
+	}
+	clock = c
+}
+
+// Set the stack capture used for every error. A nil capture restores the runtime stack capture.
+func SetStackCapture(capture StackCapture) {
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	stackCapture = capture
+}
+
+// Create a trace entry, mostly useful to implement a StackCapture
+func NewKhataTrace(file string, line int, functionName string) KhataTrace {
+	return KhataTrace{
+		file:         file,
+		line:         line,
+		functionName: functionName,
+	}
+}
+
+// Create a trace entry standing for the given number of frames folded into it, mostly useful to decode traces
+func NewFoldedKhataTrace(file string, line int, functionName string, folded int) KhataTrace {
+	trace := NewKhataTrace(file, line, functionName)
+	trace.folded = folded
+	return trace
+}
+
+// Returns the current time from the given clock, or the package clock if it is nil
+func now(c Clock) time.Time {
+	if c == nil {
+		settingsMutex.RLock()
+		c = clock
+		settingsMutex.RUnlock()
+	}
+	return c.Now().UTC()
+}
+
+func currentStackCapture() StackCapture {
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+
+	return stackCapture
+}
@@ -0,0 +1,84 @@
+package khata_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+type fixedStack struct{}
+
+func (fixedStack) Trace() []khata.KhataTrace {
+	return []khata.KhataTrace{khata.NewKhataTrace("main.go", 10, "main.main")}
+}
+
+func (fixedStack) Caller() khata.KhataTrace {
+	return khata.NewKhataTrace("main.go", 5, "main.run")
+}
+
+func TestKhataClock(t *testing.T) {
+	current := time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)
+	khata.SetClock(khata.ClockFunc(func() time.Time { return current }))
+	defer khata.SetClock(nil)
+
+	k := khata.New("This is an error message")
+
+	if !k.CreatedAt().Equal(current) {
+		t.Error("New() did not use the package clock")
+		return
+	}
+
+	current = current.Add(time.Second)
+	k.Explain("This is an explanation")
+
+	if k.Explanations()[0].Elapsed != time.Second {
+		t.Error("Explain() did not use the package clock")
+		return
+	}
+
+	current = current.Add(time.Second)
+	k.MarkHandled()
+	current = current.Add(time.Second)
+	k.MarkHandled()
+
+	if !k.HandledAt().Equal(time.Date(2023, 7, 2, 4, 27, 37, 0, time.UTC)) {
+		t.Error("MarkHandled() did not keep the first handling time")
+		return
+	}
+
+	if !strings.Contains(k.ToJSON(), `"handledAt":"2023-07-02T04:27:37Z"`) {
+		t.Error("ToJSON() did not use the handling time")
+		return
+	}
+}
+
+func TestKhataTemplateClock(t *testing.T) {
+	templateTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	template := khata.NewTemplate().SetClock(khata.ClockFunc(func() time.Time { return templateTime }))
+
+	k := template.Extend().New()
+
+	if !k.CreatedAt().Equal(templateTime) {
+		t.Error("New() did not use the template clock")
+		return
+	}
+}
+
+func TestKhataStackCapture(t *testing.T) {
+	khata.SetStackCapture(fixedStack{})
+	defer khata.SetStackCapture(nil)
+
+	k := khata.New("This is an error message").Explain("This is an explanation")
+
+	if k.Explanations()[0].FunctionName != "main.run" || k.Explanations()[0].Line != 5 {
+		t.Error("Explain() did not use the stack capture")
+		return
+	}
+
+	if len(k.Trace()) != 1 || k.Trace()[0].FunctionName() != "main.main" {
+		t.Error("Trace() did not use the stack capture")
+		return
+	}
+}
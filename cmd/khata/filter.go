@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+// Flag accepting a value several times
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Criteria a record must match to be displayed
+type filter struct {
+	types      []string
+	codes      []string
+	exitCodes  []int
+	properties map[string]string
+	since      time.Time
+	until      time.Time
+}
+
+func newFilter(types, codes, exitCodes, properties multiFlag, since, until string, now time.Time) (*filter, error) {
+	f := &filter{
+		types:      splitList(types),
+		codes:      splitList(codes),
+		properties: map[string]string{},
+	}
+
+	var err error
+
+	if f.exitCodes, err = parseInts(exitCodes); err != nil {
+		return nil, fmt.Errorf("invalid -exit-code: %w", err)
+	}
+
+	for _, property := range properties {
+		key, value, ok := strings.Cut(property, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid -prop %q: expected key=value", property)
+		}
+		f.properties[key] = value
+	}
+
+	if f.since, err = parseTime(since, now); err != nil {
+		return nil, fmt.Errorf("invalid -since: %w", err)
+	}
+
+	if f.until, err = parseTime(until, now); err != nil {
+		return nil, fmt.Errorf("invalid -until: %w", err)
+	}
+
+	return f, nil
+}
+
+func (f *filter) match(k *khata.Khata) bool {
+	if len(f.types) != 0 && !k.IsAnyType(f.types...) {
+		return false
+	}
+
+	if len(f.codes) != 0 && !f.matchCode(k) {
+		return false
+	}
+
+	if len(f.exitCodes) != 0 && !k.IsAnyExitCode(f.exitCodes...) {
+		return false
+	}
+
+	for key, value := range f.properties {
+		if !k.HasProperty(key) || formatProperty(k.GetProperty(key)) != value {
+			return false
+		}
+	}
+
+	if !f.since.IsZero() && k.CreatedAt().Before(f.since) {
+		return false
+	}
+
+	if !f.until.IsZero() && k.CreatedAt().After(f.until) {
+		return false
+	}
+
+	return true
+}
+
+// Codes are matched like CodePattern, by number or by name pattern like AUTH.*
+func (f *filter) matchCode(k *khata.Khata) bool {
+	for _, code := range f.codes {
+		if k.MatchCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Numbers decoded from JSON are float64, they are formatted without exponent
+// so large IDs like 123456789012 match as they are written
+func formatProperty(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func parseInts(values []string) ([]int, error) {
+	var ints []int
+	for _, item := range splitList(values) {
+		i, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
+
+// Accepts RFC 3339 timestamps or durations relative to now, like 15m for "15 minutes ago"
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
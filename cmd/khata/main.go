@@ -0,0 +1,208 @@
+// Command khata pretty-prints and filters streams of khata errors serialized with ToJSON.
+//
+// It reads one JSON document per line from the given files, or from the standard
+// input, and renders each khata error the same way Debug() does. Lines that are
+// not khata errors are skipped, unless -passthrough is set.
+//
+//	khata [flags] [file ...]
+//
+// Examples:
+//
+//	tail -f app.log | khata -type HTTP -code 500,503
+//	khata -code 'AUTH.*' -prop userID=42 -since 1h app.log
+//	khata -summary app.log
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/internal/colors"
+)
+
+// Maximum length of a line, khata errors with large traces can be long
+const maxLineSize = 16 * 1024 * 1024
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("khata", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	var types, codes, exitCodes, properties multiFlag
+	flags.Var(&types, "type", "only show errors of the given `types` (comma separated, repeatable)")
+	flags.Var(&codes, "code", "only show errors with the given `codes` or code name patterns like AUTH.* (comma separated, repeatable)")
+	flags.Var(&exitCodes, "exit-code", "only show errors with the given exit `codes` (comma separated, repeatable)")
+	flags.Var(&properties, "prop", "only show errors with the property `key=value` (repeatable)")
+	since := flags.String("since", "", "only show errors created after the RFC 3339 `time`, or the duration ago (e.g. 1h)")
+	until := flags.String("until", "", "only show errors created before the RFC 3339 `time`, or the duration ago (e.g. 10m)")
+	summary := flags.Bool("summary", false, "print the number of errors per fingerprint instead of the errors")
+	passthrough := flags.Bool("passthrough", false, "print the lines that are not khata errors as they are")
+	noColor := flags.Bool("no-color", os.Getenv("NO_COLOR") != "", "disable colors")
+	config := khata.ConfigFromEnv()
+	separator := string(os.PathListSeparator)
+	funcPrefix := flags.String("func-prefix", strings.Join(config.FuncTrimPrefixes, separator), "`prefixes` trimmed from the function names, separated by "+separator)
+	pathPrefix := flags.String("path-prefix", strings.Join(config.PathTrimPrefixes, separator), "`prefixes` trimmed from the file paths, separated by "+separator)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	f, err := newFilter(types, codes, exitCodes, properties, *since, *until, time.Now().UTC())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	config.FuncTrimPrefixes = filepath.SplitList(*funcPrefix)
+	config.PathTrimPrefixes = filepath.SplitList(*pathPrefix)
+
+	if *noColor {
+		colors.Disable()
+		config.ColorMode = khata.ColorNever
+	}
+
+	khata.SetConfig(config)
+
+	var groups *summaryGroups
+	if *summary {
+		groups = &summaryGroups{byFingerprint: map[string]*summaryGroup{}}
+	}
+
+	handle := func(line []byte) {
+		k, err := khata.FromJSON(line)
+
+		if err != nil {
+			if *passthrough && !*summary {
+				fmt.Fprintf(stdout, "%s\n", line)
+			}
+			return
+		}
+
+		if !f.match(k) {
+			return
+		}
+
+		if groups != nil {
+			groups.add(k)
+			return
+		}
+
+		k.DebugTo(stdout)
+	}
+
+	inputs := flags.Args()
+	exitCode := 0
+
+	if len(inputs) == 0 {
+		if err := scan(stdin, handle); err != nil {
+			fmt.Fprintln(stderr, err)
+			exitCode = 1
+		}
+	}
+
+	for _, input := range inputs {
+		file, err := os.Open(input)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exitCode = 1
+			continue
+		}
+
+		if err := scan(file, handle); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", input, err)
+			exitCode = 1
+		}
+
+		file.Close()
+	}
+
+	if groups != nil {
+		groups.print(stdout)
+	}
+
+	return exitCode
+}
+
+func scan(r io.Reader, handle func(line []byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		handle(scanner.Bytes())
+	}
+
+	return scanner.Err()
+}
+
+type summaryGroup struct {
+	fingerprint string
+	count       int
+	sample      *khata.Khata
+	first       time.Time
+	last        time.Time
+}
+
+type summaryGroups struct {
+	byFingerprint map[string]*summaryGroup
+	order         []*summaryGroup
+}
+
+func (g *summaryGroups) add(k *khata.Khata) {
+	fingerprint := k.Fingerprint()
+	group, ok := g.byFingerprint[fingerprint]
+
+	if !ok {
+		group = &summaryGroup{fingerprint: fingerprint, sample: k, first: k.CreatedAt(), last: k.CreatedAt()}
+		g.byFingerprint[fingerprint] = group
+		g.order = append(g.order, group)
+	}
+
+	group.count++
+
+	if k.CreatedAt().Before(group.first) {
+		group.first = k.CreatedAt()
+	}
+
+	if k.CreatedAt().After(group.last) {
+		group.last = k.CreatedAt()
+	}
+}
+
+func (g *summaryGroups) print(w io.Writer) {
+	sort.SliceStable(g.order, func(i, j int) bool {
+		return g.order[i].count > g.order[j].count
+	})
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "%sCOUNT\tFINGERPRINT\tTYPE\tCODE\tFIRST SEEN\tLAST SEEN\tERROR%s\n", colors.BoldWhite, colors.Reset)
+
+	for _, group := range g.order {
+		fmt.Fprintf(
+			table,
+			"%d\t%s%s%s\t%s\t%d\t%s\t%s\t%s\n",
+			group.count,
+			colors.Cyan,
+			group.fingerprint,
+			colors.Reset,
+			group.sample.Type(),
+			group.sample.Code(),
+			group.first.Format(time.RFC3339),
+			group.last.Format(time.RFC3339),
+			group.sample.Error(),
+		)
+	}
+
+	table.Flush()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func testStream() string {
+	notFound := khata.New("Not Found").SetType("HTTP").SetCode(404).SetProperty("userID", 42).Explain("This is an explanation of not found")
+	internal := khata.New("Internal").SetType("HTTP").SetCode(500).SetProperty("userID", 123456789012)
+	other := khata.New("Other").SetType("Database").SetExitCode(3).SetCodeName("DB.CONNECTION.REFUSED")
+
+	return strings.Join([]string{
+		notFound.ToJSON(),
+		"this is not a khata error",
+		internal.ToJSON(),
+		`{"level":"info","msg":"json but not khata"}`,
+		other.ToJSON(),
+		notFound.ToJSON(),
+	}, "\n")
+}
+
+func runCLI(t *testing.T, args ...string) string {
+	var stdout, stderr bytes.Buffer
+
+	if code := run(append([]string{"-no-color"}, args...), strings.NewReader(testStream()), &stdout, &stderr); code != 0 {
+		t.Fatalf("run() returned %d: %s", code, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func TestRenderStream(t *testing.T) {
+	output := runCLI(t)
+
+	if strings.Count(output, "=== Details") != 4 {
+		t.Error("run() did not render every khata error")
+		return
+	}
+
+	if strings.Contains(output, "this is not a khata error") {
+		t.Error("run() printed a line that is not a khata error")
+		return
+	}
+
+	if !strings.Contains(runCLI(t, "-passthrough"), "this is not a khata error") {
+		t.Error("run() did not pass the other lines through with -passthrough")
+		return
+	}
+}
+
+func TestFilterStream(t *testing.T) {
+	output := runCLI(t, "-type", "HTTP", "-code", "500")
+
+	if strings.Count(output, "=== Details") != 1 || !strings.Contains(output, "Internal") {
+		t.Error("run() did not filter by type and code")
+		return
+	}
+
+	output = runCLI(t, "-code", "AUTH.*,DB.*")
+
+	if strings.Count(output, "=== Details") != 1 || !strings.Contains(output, "Other") {
+		t.Error("run() did not filter by code name pattern")
+		return
+	}
+
+	output = runCLI(t, "-prop", "userID=42")
+
+	if strings.Count(output, "=== Details") != 2 || strings.Contains(output, "Internal") {
+		t.Error("run() did not filter by property")
+		return
+	}
+
+	output = runCLI(t, "-prop", "userID=123456789012")
+
+	if strings.Count(output, "=== Details") != 1 || !strings.Contains(output, "Internal") {
+		t.Error("run() did not filter by a large integer property")
+		return
+	}
+
+	output = runCLI(t, "-exit-code", "3")
+
+	if strings.Count(output, "=== Details") != 1 || !strings.Contains(output, "Other") {
+		t.Error("run() did not filter by exit code")
+		return
+	}
+
+	if output := runCLI(t, "-until", "1h"); strings.Contains(output, "=== Details") {
+		t.Error("run() did not filter by time range")
+		return
+	}
+}
+
+func TestSummaryStream(t *testing.T) {
+	output := runCLI(t, "-summary")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	if len(lines) != 4 {
+		t.Error("run() did not print one line per fingerprint")
+		return
+	}
+
+	if !strings.HasPrefix(lines[1], "2 ") || !strings.Contains(lines[1], "Not Found") {
+		t.Error("run() did not count the errors sharing a fingerprint")
+		return
+	}
+}
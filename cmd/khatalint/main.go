@@ -0,0 +1,16 @@
+// Command khatalint reports common mistakes made while using khata errors.
+//
+// It can be run on its own or through go vet:
+//
+//	khatalint ./...
+//	go vet -vettool=$(which khatalint) ./...
+package main
+
+import (
+	"github.com/cmseguin/khata/khatalint"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(khatalint.Analyzer)
+}
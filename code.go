@@ -0,0 +1,129 @@
+package khata
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Separator of the segments of a code name
+const CODE_SEPARATOR = "."
+
+// KhataCode identifies an error with a namespaced name like AUTH.TOKEN.EXPIRED,
+// a number like an HTTP status, or both. Unset numbers are DEFAULT_ERROR_CODE,
+// so codes should be created with NewCode, NamedCode or NumericCode.
+type KhataCode struct {
+	name   string
+	number int
+}
+
+// Create a code with a name and a number
+func NewCode(name string, number int) KhataCode {
+	return KhataCode{name: name, number: number}
+}
+
+// Create a code with a name only
+func NamedCode(name string) KhataCode {
+	return KhataCode{name: name, number: DEFAULT_ERROR_CODE}
+}
+
+// Create a code with a number only
+func NumericCode(number int) KhataCode {
+	return KhataCode{number: number}
+}
+
+// Returns the name of the code, empty if it has none
+func (c KhataCode) Name() string {
+	return c.name
+}
+
+// Returns the number of the code, DEFAULT_ERROR_CODE if it has none
+func (c KhataCode) Number() int {
+	return c.number
+}
+
+// Returns the segments of the name, like [AUTH TOKEN EXPIRED]
+func (c KhataCode) Segments() []string {
+	if c.name == "" {
+		return nil
+	}
+	return strings.Split(c.name, CODE_SEPARATOR)
+}
+
+// Returns a code whose name is extended with the given name, keeping the number
+func (c KhataCode) Extend(name string) KhataCode {
+	return KhataCode{name: joinCodeNames(c.name, name), number: c.number}
+}
+
+// Returns the name and the number, like AUTH.TOKEN.EXPIRED (401)
+func (c KhataCode) String() string {
+	switch {
+	case c.name == "":
+		return strconv.Itoa(c.number)
+	case c.number == DEFAULT_ERROR_CODE:
+		return c.name
+	default:
+		return c.name + " (" + strconv.Itoa(c.number) + ")"
+	}
+}
+
+// Check if the code matches the pattern. Numeric patterns are compared with the
+// number. Other patterns are compared with the name segment by segment, where *
+// matches any segment, and a trailing * matches one or more segments:
+// AUTH.* matches AUTH.TOKEN and AUTH.TOKEN.EXPIRED, but not AUTH.
+func (c KhataCode) Matches(pattern string) bool {
+	if number, err := strconv.Atoi(pattern); err == nil {
+		return c.number == number
+	}
+
+	if c.name == "" {
+		return false
+	}
+
+	patterns := strings.Split(pattern, CODE_SEPARATOR)
+	segments := c.Segments()
+
+	for i, p := range patterns {
+		if i == len(segments) {
+			return false
+		}
+
+		if p == "*" {
+			if i == len(patterns)-1 {
+				return true
+			}
+			continue
+		}
+
+		if p != segments[i] {
+			return false
+		}
+	}
+
+	return len(patterns) == len(segments)
+}
+
+// Joins a child name to its parent name, unless the child name is already under the parent
+func joinCodeNames(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case child == parent || strings.HasPrefix(child, parent+CODE_SEPARATOR):
+		return child
+	default:
+		return parent + CODE_SEPARATOR + child
+	}
+}
+
+// Check if the code has the name and the number set on the other code.
+// Unset names and numbers are not compared.
+func (c KhataCode) Has(other KhataCode) bool {
+	if other.name != "" && other.name != c.name {
+		return false
+	}
+	if other.number != DEFAULT_ERROR_CODE && other.number != c.number {
+		return false
+	}
+	return other.name != "" || other.number == c.number
+}
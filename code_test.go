@@ -0,0 +1,103 @@
+package khata_test
+
+import (
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestKhataCodeMatches(t *testing.T) {
+	code := khata.NewCode("AUTH.TOKEN.EXPIRED", 401)
+
+	for _, pattern := range []string{"AUTH.TOKEN.EXPIRED", "AUTH.*", "AUTH.TOKEN.*", "*.TOKEN.EXPIRED", "*", "401"} {
+		if !code.Matches(pattern) {
+			t.Error("Matches() did not match " + pattern)
+			return
+		}
+	}
+
+	for _, pattern := range []string{"AUTH", "AUTH.TOKEN", "AUTH.TOKEN.EXPIRED.*", "*.EXPIRED", "BILLING.*", "404"} {
+		if code.Matches(pattern) {
+			t.Error("Matches() matched " + pattern)
+			return
+		}
+	}
+
+	if khata.NumericCode(404).Matches("*") {
+		t.Error("Matches() matched a name pattern on a code without name")
+		return
+	}
+}
+
+func TestKhataCodeString(t *testing.T) {
+	if khata.NewCode("AUTH.TOKEN.EXPIRED", 401).String() != "AUTH.TOKEN.EXPIRED (401)" ||
+		khata.NamedCode("AUTH").String() != "AUTH" ||
+		khata.NumericCode(404).String() != "404" {
+		t.Error("String() did not render the name and the number")
+		return
+	}
+}
+
+func TestKhataTemplateCodeName(t *testing.T) {
+	auth := khata.NewTemplate().SetCodeName("AUTH").SetCode(401)
+	token := auth.Extend().SetCodeName("TOKEN")
+	expired := token.Extend().SetCodeName("EXPIRED")
+	absolute := token.Extend().SetCodeName("AUTH.TOKEN.REVOKED")
+	inherited := expired.Extend().SetType("Inherited")
+
+	if expired.CodeName() != "AUTH.TOKEN.EXPIRED" || absolute.CodeName() != "AUTH.TOKEN.REVOKED" || inherited.CodeName() != "AUTH.TOKEN.EXPIRED" {
+		t.Error("CodeName() did not extend the code name of the parent")
+		return
+	}
+
+	auth.SetCodeName("IAM")
+	if expired.ErrorCode() != khata.NewCode("IAM.TOKEN.EXPIRED", 401) {
+		t.Error("ErrorCode() did not resolve the code name at read time")
+		return
+	}
+
+	k := expired.New()
+	if k.CodeName() != "IAM.TOKEN.EXPIRED" || k.Code() != 401 {
+		t.Error("New() did not use the code of the template")
+		return
+	}
+}
+
+func TestKhataIsCode(t *testing.T) {
+	k := khata.New("This is an error message").SetErrorCode(khata.NewCode("AUTH.TOKEN.EXPIRED", 401))
+
+	if !k.IsCode(401) || !k.MatchCode("AUTH.*") || !k.IsKhataCode(khata.NamedCode("AUTH.TOKEN.EXPIRED")) || !k.IsKhataCode(khata.NumericCode(401)) {
+		t.Error("IsCode() did not match the code")
+		return
+	}
+
+	if k.IsCode(404) || k.MatchCode("BILLING.*") || k.IsKhataCode(khata.NewCode("AUTH.TOKEN.EXPIRED", 403)) {
+		t.Error("IsCode() matched another code")
+		return
+	}
+
+	if !khata.CodePattern("BILLING.*", "AUTH.*").Match(k) {
+		t.Error("CodePattern() did not match the code")
+		return
+	}
+}
+
+func TestKhataCodeNameJSON(t *testing.T) {
+	k := khata.New("This is an error message").SetErrorCode(khata.NewCode("AUTH.TOKEN.EXPIRED", 401))
+
+	decoded, err := khata.FromJSON([]byte(k.ToJSON()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if decoded.ErrorCode() != k.ErrorCode() {
+		t.Error("FromJSON() did not restore the code name and the code number")
+		return
+	}
+
+	if decoded.Fingerprint() == khata.New("This is an error message").SetCode(401).Fingerprint() {
+		t.Error("Fingerprint() did not include the code name")
+		return
+	}
+}
@@ -0,0 +1,334 @@
+package khata
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cmseguin/khata/internal/colors"
+)
+
+// ColorMode controls the colors of the console output
+type ColorMode int
+
+const (
+	// Use colors unless the NO_COLOR environment variable is set
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+// Config controls how errors are created and rendered.
+//
+// The configuration is resolved in the following order, the first one found winning:
+//
+//  1. the Config whose render methods are called, like Config.DebugTo
+//  2. the Config set with SetConfig
+//  3. the Config loaded from the environment with ConfigFromEnv, the first time it is needed
+//
+// SetConfig replaces the whole configuration. Start from ConfigFromEnv or
+// CurrentConfig to only override some of the values.
+type Config struct {
+	// Prefixes trimmed from the function names. The longest matching prefix is trimmed.
+	FuncTrimPrefixes []string
+	// Prefixes trimmed from the file paths. The longest matching prefix is trimmed.
+	PathTrimPrefixes []string
+	// Trim the path of the main module, detected from the build info, from the
+	// function names and from the file paths of binaries built with -trimpath.
+	// The last element of the module path is kept, so "github.com/org/app/db.Open"
+	// becomes "app/db.Open".
+	TrimModuleRoot bool
+	// Package patterns of the frames to keep. When empty, every frame is kept.
+	// Patterns ending with "/..." match a package and its subpackages, the others
+	// are matched with path.Match.
+	IncludePackages []string
+	// Package patterns of the frames to remove, with the same syntax as IncludePackages
+	ExcludePackages []string
+	// Fold consecutive frames of the standard library, including the runtime, into the first one
+	CollapseGOROOT bool
+	// Fold consecutive frames of the same function, from recursive calls, into the first one
+	FoldRecursion bool
+	// Maximum number of frames rendered, after the filters. Zero or less renders every frame.
+	MaxTraceDepth int
+	// Colors of the console output
+	ColorMode ColorMode
+	// Template used by New and Wrap. When nil, errors get the default values.
+	DefaultTemplate *KhataTemplate
+	// Attach a goroutine dump to the fatal errors handled by HandleKhata
+	DumpGoroutinesOnFatal bool
+	// Enable the development features, like the error pages of KhataDevServer.Middleware
+	Development bool
+}
+
+// Returns the configuration described by the environment:
+//
+//   - KHATA_FUNC_TRUNC_PREFIX: prefixes trimmed from the function names, separated by the os.PathListSeparator
+//   - KHATA_PATH_TRUNC_PREFIX or KHATA_FILE_TRUNC_PREFIX: prefixes trimmed from the file paths, separated by the os.PathListSeparator
+//   - KHATA_TRIM_MODULE_ROOT: trim the path of the main module when set to a true value
+//   - KHATA_TRACE_INCLUDE, KHATA_TRACE_EXCLUDE: package patterns of the frames to keep or remove, separated by the os.PathListSeparator
+//   - KHATA_COLLAPSE_GOROOT: fold the standard library frames when set to a true value
+//   - KHATA_FOLD_RECURSION: fold the frames of recursive calls when set to a true value
+//   - KHATA_MAX_TRACE_DEPTH: maximum number of frames rendered
+//   - KHATA_COLOR: "auto", "always" or "never"
+//   - KHATA_DUMP_GOROUTINES: attach a goroutine dump to the fatal errors handled by HandleKhata when set to a true value
+//   - KHATA_DEV: enable the development features when set to a true value
+func ConfigFromEnv() Config {
+	config := Config{
+		FuncTrimPrefixes:      splitEnvList("KHATA_FUNC_TRUNC_PREFIX"),
+		PathTrimPrefixes:      append(splitEnvList("KHATA_PATH_TRUNC_PREFIX"), splitEnvList("KHATA_FILE_TRUNC_PREFIX")...),
+		TrimModuleRoot:        envBool("KHATA_TRIM_MODULE_ROOT"),
+		IncludePackages:       splitEnvList("KHATA_TRACE_INCLUDE"),
+		ExcludePackages:       splitEnvList("KHATA_TRACE_EXCLUDE"),
+		CollapseGOROOT:        envBool("KHATA_COLLAPSE_GOROOT"),
+		FoldRecursion:         envBool("KHATA_FOLD_RECURSION"),
+		DumpGoroutinesOnFatal: envBool("KHATA_DUMP_GOROUTINES"),
+		Development:           envBool("KHATA_DEV"),
+	}
+
+	if depth, err := strconv.Atoi(os.Getenv("KHATA_MAX_TRACE_DEPTH")); err == nil {
+		config.MaxTraceDepth = depth
+	}
+
+	switch strings.ToLower(os.Getenv("KHATA_COLOR")) {
+	case "always":
+		config.ColorMode = ColorAlways
+	case "never":
+		config.ColorMode = ColorNever
+	}
+
+	return config
+}
+
+var (
+	configOnce sync.Once
+	config     *Config
+)
+
+// Set the configuration used by the package level functions and the methods of the errors
+func SetConfig(c Config) {
+	configOnce.Do(func() {})
+
+	settingsMutex.Lock()
+	defer settingsMutex.Unlock()
+
+	config = &c
+}
+
+// Returns a copy of the configuration currently used
+func CurrentConfig() Config {
+	return *currentConfig()
+}
+
+// Returns the configuration currently used, loading it from the environment the first time
+func currentConfig() *Config {
+	configOnce.Do(func() {
+		c := ConfigFromEnv()
+
+		settingsMutex.Lock()
+		defer settingsMutex.Unlock()
+
+		config = &c
+	})
+
+	settingsMutex.RLock()
+	defer settingsMutex.RUnlock()
+
+	return config
+}
+
+func splitEnvList(name string) []string {
+	values := []string{}
+	for _, value := range filepath.SplitList(os.Getenv(name)) {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func envBool(name string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(name))
+	return value
+}
+
+// Returns the path of the main module, from the build info
+var mainModule = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return info.Main.Path
+})
+
+// Returns the prefix trimmed by TrimModuleRoot: the main module path without its last element
+func moduleRoot() string {
+	if dir := path.Dir(mainModule()); dir != "." {
+		return dir + "/"
+	}
+	return ""
+}
+
+// Returns the function name without its longest configured prefix
+func (c *Config) trimFunc(funcName string) string {
+	return trimLongestPrefix(funcName, c.FuncTrimPrefixes, c.TrimModuleRoot)
+}
+
+// Returns the file path without its longest configured prefix
+func (c *Config) trimPath(filePath string) string {
+	return trimLongestPrefix(filePath, c.PathTrimPrefixes, c.TrimModuleRoot)
+}
+
+func trimLongestPrefix(value string, prefixes []string, trimModuleRoot bool) string {
+	longest := ""
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+
+	if root := moduleRoot(); trimModuleRoot && root != "" && strings.HasPrefix(value, root) && len(root) > len(longest) {
+		longest = root
+	}
+
+	return value[len(longest):]
+}
+
+// Returns the frames to render, after the trace filters:
+// package patterns, folding of the standard library and recursive frames, and maximum depth
+func (c *Config) frames(trace []KhataTrace) []KhataTrace {
+	filtered := make([]KhataTrace, 0, len(trace))
+
+	for _, frame := range trace {
+		pkg := funcPackage(frame.functionName)
+
+		if len(c.IncludePackages) != 0 && !matchPackage(pkg, c.IncludePackages) {
+			continue
+		}
+
+		if matchPackage(pkg, c.ExcludePackages) {
+			continue
+		}
+
+		if n := len(filtered); n != 0 {
+			previous := &filtered[n-1]
+
+			recursive := c.FoldRecursion && previous.functionName == frame.functionName
+			standard := c.CollapseGOROOT && isStandardPackage(pkg) && isStandardPackage(funcPackage(previous.functionName))
+
+			if recursive || standard {
+				previous.folded += frame.folded + 1
+				continue
+			}
+		}
+
+		filtered = append(filtered, frame)
+	}
+
+	if c.MaxTraceDepth > 0 && len(filtered) > c.MaxTraceDepth {
+		filtered = filtered[:c.MaxTraceDepth]
+	}
+
+	return filtered
+}
+
+// Returns the frames of the trace kept by the trace filters of the configuration,
+// as ToJSON serializes them. Useful to implement other serializations.
+func FilterTrace(trace []KhataTrace) []KhataTrace {
+	return currentConfig().frames(trace)
+}
+
+// Returns the import path of the package of the function
+func funcPackage(funcName string) string {
+	lastSlash := strings.LastIndex(funcName, "/")
+	if dot := strings.Index(funcName[lastSlash+1:], "."); dot >= 0 {
+		return funcName[:lastSlash+1+dot]
+	}
+	return funcName
+}
+
+// Returns true if the package belongs to the standard library, whose import paths have no dot in their first element
+func isStandardPackage(pkg string) bool {
+	if pkg == "" || pkg == "main" {
+		return false
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Returns true if the package matches one of the patterns
+func matchPackage(pkg string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if tree, ok := strings.CutSuffix(pattern, "/..."); ok {
+			if pkg == tree || strings.HasPrefix(pkg, tree+"/") {
+				return true
+			}
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, pkg); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// palette holds the escape codes used by the console output, empty when colors are disabled
+type palette struct {
+	Reset         string
+	Yellow        string
+	Green         string
+	Cyan          string
+	Gray          string
+	BoldWhite     string
+	BoldYellow    string
+	BoldGray      string
+	BoldBlue      string
+	BoldRed       string
+	BoldPurple    string
+	UnderlineGray string
+}
+
+func (c *Config) palette() palette {
+	if c.ColorMode == ColorNever || (c.ColorMode == ColorAuto && os.Getenv("NO_COLOR") != "") {
+		return palette{}
+	}
+
+	return palette{
+		Reset:         colors.Reset,
+		Yellow:        colors.Yellow,
+		Green:         colors.Green,
+		Cyan:          colors.Cyan,
+		Gray:          colors.Gray,
+		BoldWhite:     colors.BoldWhite,
+		BoldYellow:    colors.BoldYellow,
+		BoldGray:      colors.BoldGray,
+		BoldBlue:      colors.BoldBlue,
+		BoldRed:       colors.BoldRed,
+		BoldPurple:    colors.BoldPurple,
+		UnderlineGray: colors.UnderlineGray,
+	}
+}
+
+// Returns the color of the severity
+func (p palette) severity(s Severity) string {
+	switch s {
+	case SeverityDebug:
+		return p.BoldGray
+	case SeverityInfo:
+		return p.BoldBlue
+	case SeverityWarning:
+		return p.BoldYellow
+	case SeverityError:
+		return p.BoldRed
+	case SeverityCritical, SeverityFatal:
+		return p.BoldPurple
+	default:
+		return p.BoldRed
+	}
+}
@@ -0,0 +1,152 @@
+package khata_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+type stdlibStack struct{}
+
+func (stdlibStack) Trace() []khata.KhataTrace {
+	return []khata.KhataTrace{
+		khata.NewKhataTrace("/home/dev/app/db/db.go", 12, "github.com/org/app/db.Open"),
+		khata.NewKhataTrace("/usr/local/go/src/net/http/server.go", 2220, "net/http.HandlerFunc.ServeHTTP"),
+		khata.NewKhataTrace("/usr/local/go/src/net/http/server.go", 3210, "net/http.serverHandler.ServeHTTP"),
+		khata.NewKhataTrace("/home/dev/app/main.go", 20, "main.main"),
+		khata.NewKhataTrace("/usr/local/go/src/runtime/proc.go", 272, "runtime.main"),
+	}
+}
+
+func (stdlibStack) Caller() khata.KhataTrace {
+	return khata.NewKhataTrace("/home/dev/app/db/db.go", 10, "github.com/org/app/db.Open")
+}
+
+func TestConfigRender(t *testing.T) {
+	khata.SetStackCapture(stdlibStack{})
+	defer khata.SetStackCapture(nil)
+
+	k := khata.New("This is an error message")
+
+	config := khata.Config{
+		FuncTrimPrefixes: []string{"github.com/", "github.com/org/"},
+		PathTrimPrefixes: []string{"/home/dev/app/"},
+		CollapseGOROOT:   true,
+		MaxTraceDepth:    3,
+	}
+
+	md := config.RenderMarkdown(k)
+
+	if !strings.Contains(md, "db/db.go:12 (app/db.Open)") {
+		t.Error("Config did not trim the longest prefixes")
+		return
+	}
+
+	if !strings.Contains(md, "server.go:2220 (net/http.HandlerFunc.ServeHTTP) +1 folded frames\nmain.go:20 (main.main)\n```") {
+		t.Error("Config did not collapse the standard library frames or limit the trace depth")
+		return
+	}
+
+	buf := &strings.Builder{}
+	(&khata.Config{ColorMode: khata.ColorNever}).DebugTo(buf, k)
+
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("Config did not disable the colors")
+		return
+	}
+}
+
+type recursiveStack struct{}
+
+func (recursiveStack) Trace() []khata.KhataTrace {
+	return []khata.KhataTrace{
+		khata.NewKhataTrace("/src/tree/tree.go", 30, "github.com/org/app/tree.walk"),
+		khata.NewKhataTrace("/src/tree/tree.go", 34, "github.com/org/app/tree.walk"),
+		khata.NewKhataTrace("/src/tree/tree.go", 34, "github.com/org/app/tree.walk"),
+		khata.NewKhataTrace("/src/vendor/log.go", 5, "github.com/vendor/log.Print"),
+		khata.NewKhataTrace("/src/api/api.go", 8, "github.com/org/app/api.Handle"),
+		khata.NewKhataTrace("/src/main.go", 3, "main.main"),
+	}
+}
+
+func (recursiveStack) Caller() khata.KhataTrace {
+	return khata.NewKhataTrace("/src/tree/tree.go", 30, "github.com/org/app/tree.walk")
+}
+
+func TestConfigTraceFilters(t *testing.T) {
+	khata.SetStackCapture(recursiveStack{})
+	defer khata.SetStackCapture(nil)
+
+	defer khata.SetConfig(khata.CurrentConfig())
+	khata.SetConfig(khata.Config{
+		IncludePackages: []string{"github.com/org/app/...", "github.com/vendor/*"},
+		ExcludePackages: []string{"github.com/vendor/log"},
+		FoldRecursion:   true,
+	})
+
+	decoded, err := khata.FromJSON([]byte(khata.New("This is an error message").ToJSON()))
+	if err != nil {
+		t.Error("ToJSON() did not produce a valid document")
+		return
+	}
+
+	trace := decoded.Trace()
+
+	if len(trace) != 2 || trace[0].FunctionName() != "github.com/org/app/tree.walk" || trace[1].FunctionName() != "github.com/org/app/api.Handle" {
+		t.Error("ToJSON() did not filter the trace with the package patterns")
+		return
+	}
+
+	if trace[0].Folded() != 2 {
+		t.Error("ToJSON() did not fold the recursive frames")
+		return
+	}
+}
+
+func TestKhataTraceIsOwnCode(t *testing.T) {
+	own := khata.NewKhataTrace("khatatest.go", 1, "github.com/cmseguin/khata/khatatest.Require")
+	test := khata.NewKhataTrace("khata_test.go", 1, "github.com/cmseguin/khata_test.TestKhataTraceIsOwnCode")
+	dependency := khata.NewKhataTrace("cmp.go", 1, "github.com/google/go-cmp/cmp.Diff")
+
+	if !own.IsOwnCode() || test.IsOwnCode() || dependency.IsOwnCode() {
+		t.Error("IsOwnCode() did not compare the package with the main module")
+		return
+	}
+}
+
+func TestConfigDefaultTemplate(t *testing.T) {
+	defer khata.SetConfig(khata.CurrentConfig())
+
+	template := khata.NewTemplate().SetType("App").SetNonFatal()
+	khata.SetConfig(khata.Config{DefaultTemplate: template})
+
+	if !khata.New("This is an error message").IsInstanceOf(template) || !khata.Wrap(errors.New("This is an error message")).IsInstanceOf(template) {
+		t.Error("New() and Wrap() did not use the default template")
+		return
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	separator := string(os.PathListSeparator)
+
+	t.Setenv("KHATA_FUNC_TRUNC_PREFIX", "github.com/"+separator+"gitlab.com/")
+	t.Setenv("KHATA_PATH_TRUNC_PREFIX", "/home/dev/")
+	t.Setenv("KHATA_FILE_TRUNC_PREFIX", "/src/")
+	t.Setenv("KHATA_MAX_TRACE_DEPTH", "5")
+	t.Setenv("KHATA_COLOR", "never")
+
+	config := khata.ConfigFromEnv()
+
+	if len(config.FuncTrimPrefixes) != 2 || len(config.PathTrimPrefixes) != 2 {
+		t.Error("ConfigFromEnv() did not read the trim prefixes")
+		return
+	}
+
+	if config.MaxTraceDepth != 5 || config.ColorMode != khata.ColorNever {
+		t.Error("ConfigFromEnv() did not read the trace depth and color mode")
+		return
+	}
+}
@@ -0,0 +1,541 @@
+package khata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default number of errors kept by the development server
+const DEFAULT_DEV_SERVER_CAPACITY = 100
+
+// Number of source lines shown around each frame of the development server traces
+const devServerSnippetRadius = 3
+
+// KhataDevServer keeps the recently handled errors in a ring buffer and serves
+// a web UI and a JSON API to browse them during development:
+//
+//	GET /                  list of the errors, filtered with q, type, code and severity
+//	GET /errors/{id}       details of an error, with source snippets of its trace
+//	GET /api/errors        JSON list of the errors, with the same filters
+//	GET /api/errors/{id}   JSON representation of an error
+//
+// Traces are the creation traces of the errors. It must not be exposed in production.
+type KhataDevServer struct {
+	config   *Config
+	capacity int
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+	remove   func()
+
+	mutex   sync.RWMutex
+	entries []devServerEntry
+	pushed  int
+}
+
+type devServerEntry struct {
+	id        int
+	handledAt time.Time
+	k         *Khata
+}
+
+// Option used to configure the development server
+type DevServerOption func(*KhataDevServer)
+
+// Keep the given number of errors. The oldest errors are dropped first.
+func DevServerCapacity(capacity int) DevServerOption {
+	return func(s *KhataDevServer) {
+		if capacity > 0 {
+			s.capacity = capacity
+		}
+	}
+}
+
+// Render the errors with the given configuration instead of the package configuration
+func DevServerConfig(config Config) DevServerOption {
+	return func(s *KhataDevServer) {
+		s.config = &config
+	}
+}
+
+// Start a development server listening on addr. Every error handled by the
+// program is pushed to it, until it is closed.
+func DevServer(addr string, options ...DevServerOption) (*KhataDevServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewDevServer(options...)
+	s.listener = listener
+	s.server = &http.Server{Handler: s}
+	s.remove = AddObserver(s)
+
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// Create a development server without listening nor observing the handled
+// errors. Errors are added with Push, and it is served as an http.Handler.
+func NewDevServer(options ...DevServerOption) *KhataDevServer {
+	s := &KhataDevServer{capacity: DEFAULT_DEV_SERVER_CAPACITY}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /{$}", s.serveList)
+	s.mux.HandleFunc("GET /errors/{id}", s.serveDetail)
+	s.mux.HandleFunc("GET /api/errors", s.serveAPIList)
+	s.mux.HandleFunc("GET /api/errors/{id}", s.serveAPIDetail)
+
+	return s
+}
+
+// Returns the address the server listens on, empty if it does not listen
+func (s *KhataDevServer) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop observing the handled errors and close the listener
+func (s *KhataDevServer) Close() error {
+	if s.remove != nil {
+		s.remove()
+	}
+
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Add an error to the ring buffer, dropping the oldest one when it is full
+func (s *KhataDevServer) Push(k *Khata) {
+	// Resolve the creation traces now, so the requests only read them
+	for _, layer := range k.Chain() {
+		layer.CreationTrace()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pushed++
+	entry := devServerEntry{id: s.pushed, handledAt: k.handledTime(), k: k}
+
+	if len(s.entries) < s.capacity {
+		s.entries = append(s.entries, entry)
+		return
+	}
+	s.entries[(s.pushed-1)%s.capacity] = entry
+}
+
+// Implements Observer. Created errors are ignored.
+func (s *KhataDevServer) ErrorCreated(k *Khata) {}
+
+// Implements Observer by pushing the handled errors
+func (s *KhataDevServer) ErrorHandled(k *Khata) {
+	s.Push(k)
+}
+
+func (s *KhataDevServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Wrap a handler of the application so the errors it panics with are pushed
+// to the server and answered with the html page of RenderHTML, showing their
+// creation trace. The handler is called as is unless Development is set in the
+// configuration of the server. When the handler already started its response,
+// the error is pushed and the response is aborted.
+func (s *KhataDevServer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.currentConfig().Development {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		response := &devServerResponse{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// Aborting a response is not an error of the handler
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			k := recoveredKhata(recovered)
+			s.Push(k)
+
+			if response.started {
+				panic(http.ErrAbortHandler)
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, s.currentConfig().renderHTML(k, k.CreationTrace()))
+		}()
+
+		next.ServeHTTP(response, r)
+	})
+}
+
+// Records whether the handler started its response
+type devServerResponse struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (r *devServerResponse) WriteHeader(status int) {
+	r.started = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *devServerResponse) Write(data []byte) (int, error) {
+	r.started = true
+	return r.ResponseWriter.Write(data)
+}
+
+// Returns the wrapped writer, for http.ResponseController
+func (r *devServerResponse) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Returns the khata error a handler panicked with, wrapping the other values
+func recoveredKhata(recovered interface{}) *Khata {
+	switch value := recovered.(type) {
+	case *Khata:
+		return value
+	case error:
+		return Wrap(value)
+	default:
+		return New(fmt.Sprint(value))
+	}
+}
+
+func (s *KhataDevServer) currentConfig() *Config {
+	if s.config != nil {
+		return s.config
+	}
+	return currentConfig()
+}
+
+// Returns the errors matching the filter, from the most recent one
+func (s *KhataDevServer) list(filter devServerFilter) []devServerEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := []devServerEntry{}
+	for id := s.pushed; id > 0 && id > s.pushed-len(s.entries); id-- {
+		entry := s.entries[(id-1)%s.capacity]
+		if filter.match(entry.k) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Returns the error with the given ID, false if it was dropped or never pushed
+func (s *KhataDevServer) get(value string) (devServerEntry, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return devServerEntry{}, false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if id <= 0 || id > s.pushed || id <= s.pushed-len(s.entries) {
+		return devServerEntry{}, false
+	}
+	return s.entries[(id-1)%s.capacity], true
+}
+
+type devServerFilter struct {
+	Query    string
+	Type     string
+	Code     string
+	Severity string
+}
+
+func parseDevServerFilter(query url.Values) devServerFilter {
+	return devServerFilter{
+		Query:    strings.TrimSpace(query.Get("q")),
+		Type:     strings.TrimSpace(query.Get("type")),
+		Code:     strings.TrimSpace(query.Get("code")),
+		Severity: strings.TrimSpace(query.Get("severity")),
+	}
+}
+
+// Check if the error matches the filter: the query is searched in the message, the
+// type is compared exactly, the code is a pattern and the severity is a minimum
+func (f devServerFilter) match(k *Khata) bool {
+	if f.Query != "" && !strings.Contains(strings.ToLower(k.Error()), strings.ToLower(f.Query)) {
+		return false
+	}
+
+	if f.Type != "" && !k.IsType(f.Type) {
+		return false
+	}
+
+	if f.Code != "" && !k.MatchCode(f.Code) {
+		return false
+	}
+
+	if severity, ok := ParseSeverity(f.Severity); ok && !k.IsSeverity(severity) {
+		return false
+	}
+
+	return true
+}
+
+type devServerSummary struct {
+	ID          int    `json:"id"`
+	HandledAt   string `json:"handledAt"`
+	Error       string `json:"error"`
+	ErrorType   string `json:"errorType"`
+	ErrorCode   int    `json:"errorCode"`
+	CodeName    string `json:"codeName,omitempty"`
+	Severity    string `json:"severity"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func newDevServerSummary(entry devServerEntry) devServerSummary {
+	return devServerSummary{
+		ID:          entry.id,
+		HandledAt:   formatJSONTime(entry.handledAt),
+		Error:       entry.k.Error(),
+		ErrorType:   entry.k.errorType,
+		ErrorCode:   entry.k.errorCode,
+		CodeName:    entry.k.codeName,
+		Severity:    entry.k.Severity().String(),
+		Fingerprint: entry.k.Fingerprint(),
+	}
+}
+
+func (s *KhataDevServer) serveAPIList(w http.ResponseWriter, r *http.Request) {
+	summaries := []devServerSummary{}
+	for _, entry := range s.list(parseDevServerFilter(r.URL.Query())) {
+		summaries = append(summaries, newDevServerSummary(entry))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+func (s *KhataDevServer) serveAPIDetail(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.get(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	entry.k.WriteJSON(w, JSONCreationTrace(), JSONStringFallback())
+}
+
+type devServerListPage struct {
+	Filter     devServerFilter
+	Severities []string
+	Errors     []devServerSummary
+}
+
+func (s *KhataDevServer) serveList(w http.ResponseWriter, r *http.Request) {
+	page := devServerListPage{
+		Filter:     parseDevServerFilter(r.URL.Query()),
+		Severities: []string{},
+		Errors:     []devServerSummary{},
+	}
+
+	for severity := SeverityDebug; severity <= SeverityFatal; severity++ {
+		page.Severities = append(page.Severities, severity.String())
+	}
+
+	for _, entry := range s.list(page.Filter) {
+		page.Errors = append(page.Errors, newDevServerSummary(entry))
+	}
+
+	s.render(w, "list", page)
+}
+
+type devServerFrame struct {
+	reportTrace
+	Source []devServerLine
+}
+
+type devServerLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+type devServerDetailPage struct {
+	report
+	ID          int
+	Fingerprint string
+	Frames      []devServerFrame
+}
+
+func (s *KhataDevServer) serveDetail(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.get(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	c := s.currentConfig()
+	page := devServerDetailPage{
+		report:      c.newReport(entry.k, nil),
+		ID:          entry.id,
+		Fingerprint: entry.k.Fingerprint(),
+		Frames:      []devServerFrame{},
+	}
+
+	files := map[string][]string{}
+	for _, trace := range c.frames(entry.k.CreationTrace()) {
+		page.Frames = append(page.Frames, devServerFrame{
+			reportTrace: c.reportFrame(trace),
+			Source:      sourceSnippet(files, trace.file, trace.line),
+		})
+	}
+
+	s.render(w, "detail", page)
+}
+
+func (s *KhataDevServer) render(w http.ResponseWriter, name string, data interface{}) {
+	b := &bytes.Buffer{}
+
+	if err := devServerPages.ExecuteTemplate(b, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b.Bytes())
+}
+
+// Returns the lines around the given line of the file, nil if the file cannot be read.
+// Files are read once per page and kept in files.
+func sourceSnippet(files map[string][]string, file string, line int) []devServerLine {
+	lines, ok := files[file]
+	if !ok {
+		lines = readSourceLines(file)
+		files[file] = lines
+	}
+
+	if line <= 0 || line > len(lines) {
+		return nil
+	}
+
+	first := max(line-devServerSnippetRadius, 1)
+	last := min(line+devServerSnippetRadius, len(lines))
+
+	snippet := make([]devServerLine, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		snippet = append(snippet, devServerLine{
+			Number:  number,
+			Text:    lines[number-1],
+			Current: number == line,
+		})
+	}
+	return snippet
+}
+
+func readSourceLines(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// The pages share the explanations and entries templates of the html report
+var devServerPages = template.Must(template.Must(htmlReport.Clone()).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h1.error, h1.fatal, td.error, td.fatal, td.critical { color: #c0392b; } h1.warning, td.warning { color: #d68910; } h1.info, h1.debug { color: #2471a3; }
+h2 { font-size: 1.1em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+code, pre { font-family: ui-monospace, monospace; font-size: 0.9em; }
+pre { margin: 0.3em 0; background: #f6f6f6; padding: 0.3em; }
+pre span.current { background: #fde2a8; }
+div.own > code { font-weight: bold; }
+form input, form select { margin-right: 0.6em; }
+</style>
+</head>
+<body>
+<p><a href="/">All errors</a></p>
+{{end}}
+{{define "list"}}{{template "header" "khata errors"}}
+<h1>Handled errors</h1>
+<form method="get" action="/">
+<input name="q" placeholder="Message" value="{{.Filter.Query}}">
+<input name="type" placeholder="Type" value="{{.Filter.Type}}">
+<input name="code" placeholder="Code, like AUTH.* or 404" value="{{.Filter.Code}}">
+<select name="severity"><option value="">Any severity</option>
+{{range .Severities}}<option value="{{.}}"{{if eq . $.Filter.Severity}} selected{{end}}>{{.}} and above</option>
+{{end}}</select>
+<button type="submit">Filter</button>
+</form>
+{{if .Errors}}<table>
+<tr><th>#</th><th>Handled At</th><th>Severity</th><th>Type</th><th>Code</th><th>Message</th></tr>
+{{range .Errors}}<tr><td><a href="/errors/{{.ID}}">{{.ID}}</a></td><td>{{.HandledAt}}</td><td class="{{.Severity}}">{{.Severity}}</td><td>{{.ErrorType}}</td><td>{{if .CodeName}}{{.CodeName}} {{end}}{{.ErrorCode}}</td><td><a href="/errors/{{.ID}}">{{.Error}}</a></td></tr>
+{{end}}</table>{{else}}<p><em>No errors</em></p>{{end}}
+</body>
+</html>
+{{end}}
+{{define "detail"}}{{template "header" .Message}}
+<h1 class="{{.Severity}}">#{{.ID}} {{.Message}}</h1>
+<p>Fingerprint <code>{{.Fingerprint}}</code> · <a href="/api/errors/{{.ID}}">JSON</a></p>
+<h2>Explanations</h2>
+{{template "explanations" .Explanations}}
+<h2>Trace</h2>
+{{range .Frames}}<div{{if .OwnCode}} class="own"{{end}}><code>{{.Function}}</code> <code>{{.Location}}</code>{{if .Folded}} <em>+{{.Folded}} folded frames</em>{{end}}
+{{if .Source}}<details{{if .OwnCode}} open{{end}}><summary>Source</summary><pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%4d" .Number}}  {{.Text}}</span>
+{{end}}</pre></details>{{end}}</div>
+{{end}}
+<h2>Details</h2>
+{{template "entries" .Details}}
+{{if .Environment}}<h2>Environment</h2>
+{{template "entries" .Environment}}{{end}}
+{{if .Properties}}<h2>Properties</h2>
+{{template "entries" .Properties}}{{end}}
+{{range .Causes}}<h2>Caused by {{.Message}} ({{.Type}}, code {{.Code}})</h2>
+{{template "explanations" .Explanations}}
+{{if .Properties}}{{template "entries" .Properties}}{{end}}
+{{end}}</body>
+</html>
+{{end}}`))
@@ -0,0 +1,202 @@
+package khata_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func getDevServer(t *testing.T, handler http.Handler, target string) (int, string) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	return recorder.Code, string(body)
+}
+
+func TestDevServerList(t *testing.T) {
+	server := khata.NewDevServer()
+
+	server.Push(khata.New("token expired").SetCodeName("AUTH.TOKEN.EXPIRED").SetNonFatal())
+	server.Push(khata.New("disk full").SetType("Storage"))
+
+	var summaries []struct {
+		ID       int    `json:"id"`
+		Error    string `json:"error"`
+		CodeName string `json:"codeName"`
+	}
+
+	_, body := getDevServer(t, server, "/api/errors")
+	if json.Unmarshal([]byte(body), &summaries) != nil || len(summaries) != 2 || summaries[0].Error != "disk full" || summaries[1].CodeName != "AUTH.TOKEN.EXPIRED" {
+		t.Error("/api/errors did not list the errors from the most recent one")
+		return
+	}
+
+	for _, query := range []string{"?code=AUTH.*", "?q=EXPIRED", "?type=KhataError", "?severity=error&q=token"} {
+		_, body := getDevServer(t, server, "/api/errors"+query)
+		if json.Unmarshal([]byte(body), &summaries) != nil || len(summaries) != 1 || summaries[0].ID != 1 {
+			t.Error("/api/errors did not filter the errors with " + query)
+			return
+		}
+	}
+
+	if _, body := getDevServer(t, server, "/?type=Storage"); !strings.Contains(body, "disk full") || strings.Contains(body, "token expired") {
+		t.Error("/ did not list the filtered errors")
+		return
+	}
+}
+
+func TestDevServerDetail(t *testing.T) {
+	server := khata.NewDevServer()
+
+	cause := khata.New("connection refused")
+	server.Push(khata.Wrap(cause).SetProperty("host", "db-1").Explain("Could not load the user"))
+
+	status, body := getDevServer(t, server, "/errors/1")
+	if status != http.StatusOK {
+		t.Error("/errors/1 did not find the error")
+		return
+	}
+
+	for _, expected := range []string{"Could not load the user", "db-1", "Caused by connection refused", `server.Push(khata.Wrap(cause)`} {
+		if !strings.Contains(body, expected) {
+			t.Error("/errors/1 did not render " + expected)
+			return
+		}
+	}
+
+	_, body = getDevServer(t, server, "/api/errors/1")
+	decoded, err := khata.FromJSON([]byte(body))
+	if err != nil || decoded.Trace()[0].FunctionName() != "github.com/cmseguin/khata_test.TestDevServerDetail" {
+		t.Error("/api/errors/1 did not serve the error with its creation trace")
+		return
+	}
+}
+
+func TestDevServerCapacity(t *testing.T) {
+	server := khata.NewDevServer(khata.DevServerCapacity(2))
+
+	for _, message := range []string{"first", "second", "third"} {
+		server.Push(khata.New(message))
+	}
+
+	if status, _ := getDevServer(t, server, "/errors/1"); status != http.StatusNotFound {
+		t.Error("Push() did not drop the oldest error")
+		return
+	}
+
+	if _, body := getDevServer(t, server, "/errors/3"); !strings.Contains(body, "third") {
+		t.Error("Push() did not keep the most recent error")
+		return
+	}
+}
+
+func TestDevServerObserver(t *testing.T) {
+	server, err := khata.DevServer("127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	khata.New("handled while listening").MarkHandled()
+	server.Close()
+	khata.New("handled after closing").MarkHandled()
+
+	_, body := getDevServer(t, server, "/api/errors")
+	if !strings.Contains(body, "handled while listening") || strings.Contains(body, "handled after closing") {
+		t.Error("DevServer() did not receive the errors handled until it was closed")
+		return
+	}
+}
+
+func newDevelopmentServer() *khata.KhataDevServer {
+	config := khata.CurrentConfig()
+	config.Development = true
+	return khata.NewDevServer(khata.DevServerConfig(config))
+}
+
+func TestDevServerMiddleware(t *testing.T) {
+	server := newDevelopmentServer()
+	handler := server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(khata.New("<b>payment declined</b>").SetType("Payment"))
+	}))
+
+	status, body := getDevServer(t, handler, "/checkout")
+	if status != http.StatusInternalServerError || !strings.Contains(body, "&lt;b&gt;payment declined&lt;/b&gt;") {
+		t.Error("Middleware() did not render the error the handler panicked with")
+		return
+	}
+
+	if !strings.Contains(body, "devserver_test.go") {
+		t.Error("Middleware() did not render the creation trace of the error")
+		return
+	}
+
+	if _, body := getDevServer(t, server, "/api/errors?type=Payment"); !strings.Contains(body, "payment declined") {
+		t.Error("Middleware() did not push the error to the server")
+		return
+	}
+
+	handler = server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("index out of range")
+	}))
+
+	if status, body := getDevServer(t, handler, "/"); status != http.StatusInternalServerError || !strings.Contains(body, "index out of range") {
+		t.Error("Middleware() did not wrap the values that are not errors")
+		return
+	}
+}
+
+func TestDevServerMiddlewareStartedResponse(t *testing.T) {
+	server := newDevelopmentServer()
+	handler := server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "partial")
+		panic(khata.New("stream interrupted"))
+	}))
+
+	recorder := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if recover() != http.ErrAbortHandler {
+				t.Error("Middleware() did not abort the response the handler started")
+			}
+		}()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if recorder.Code != http.StatusAccepted || recorder.Body.String() != "partial" {
+		t.Error("Middleware() wrote the error page after the response of the handler")
+		return
+	}
+
+	if _, body := getDevServer(t, server, "/api/errors"); !strings.Contains(body, "stream interrupted") {
+		t.Error("Middleware() did not push the error of a started response")
+		return
+	}
+}
+
+func TestDevServerMiddlewareProduction(t *testing.T) {
+	config := khata.CurrentConfig()
+	config.Development = false
+
+	server := khata.NewDevServer(khata.DevServerConfig(config))
+	handler := server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(khata.New("payment declined"))
+	}))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Middleware() recovered the panic outside of development mode")
+		}
+	}()
+
+	getDevServer(t, handler, "/")
+}
@@ -0,0 +1,115 @@
+package khata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/cmseguin/khata/internal/goroutine"
+)
+
+// KhataEnvironment describes the goroutine and the process that created an error
+type KhataEnvironment struct {
+	// ID of the goroutine that captured the environment
+	Goroutine uint64 `json:"goroutine"`
+	// pprof labels of the context given to CaptureEnvironment
+	Labels   map[string]string `json:"labels,omitempty"`
+	Hostname string            `json:"hostname,omitempty"`
+	PID      int               `json:"pid"`
+	// Path and version of the main module, from the build info
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"moduleVersion,omitempty"`
+	GoVersion     string `json:"goVersion,omitempty"`
+	VCSRevision   string `json:"vcsRevision,omitempty"`
+	VCSTime       string `json:"vcsTime,omitempty"`
+	VCSModified   bool   `json:"vcsModified,omitempty"`
+}
+
+// The parts of the environment that do not change during the life of the process
+var processEnvironment = sync.OnceValue(func() KhataEnvironment {
+	env := KhataEnvironment{PID: os.Getpid()}
+	env.Hostname, _ = os.Hostname()
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		env.Module = info.Main.Path
+		env.ModuleVersion = info.Main.Version
+		env.GoVersion = info.GoVersion
+
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				env.VCSRevision = setting.Value
+			case "vcs.time":
+				env.VCSTime = setting.Value
+			case "vcs.modified":
+				env.VCSModified = setting.Value == "true"
+			}
+		}
+	}
+
+	return env
+})
+
+// Returns the environment of the current goroutine, with the pprof labels of the context
+func captureEnvironment(ctx context.Context) *KhataEnvironment {
+	env := processEnvironment()
+	env.Goroutine = goroutine.ID()
+
+	if ctx != nil {
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			if env.Labels == nil {
+				env.Labels = map[string]string{}
+			}
+			env.Labels[key] = value
+			return true
+		})
+	}
+
+	return &env
+}
+
+// Capture the goroutine and process context of the error, with the pprof labels of the context.
+// Errors created from a template capturing the environment already have it, without the labels.
+func (k *Khata) CaptureEnvironment(ctx context.Context) *Khata {
+	k.environment = captureEnvironment(ctx)
+	return k
+}
+
+// Returns the goroutine and process context of the error, or nil if it was not captured
+func (k *Khata) Environment() *KhataEnvironment {
+	return k.environment
+}
+
+// Returns the captured values to render, leaving out the unknown ones
+func (env *KhataEnvironment) entries() map[string]interface{} {
+	entries := map[string]interface{}{
+		"Goroutine": fmt.Sprintf("g%d", env.Goroutine),
+		"PID":       env.PID,
+	}
+
+	for name, value := range map[string]string{
+		"Hostname":       env.Hostname,
+		"Module":         env.Module,
+		"Module Version": env.ModuleVersion,
+		"Go Version":     env.GoVersion,
+		"VCS Revision":   env.VCSRevision,
+		"VCS Time":       env.VCSTime,
+	} {
+		if value != "" {
+			entries[name] = value
+		}
+	}
+
+	if env.VCSModified {
+		entries["VCS Modified"] = true
+	}
+
+	for key, value := range env.Labels {
+		entries["Label "+key] = value
+	}
+
+	return entries
+}
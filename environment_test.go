@@ -0,0 +1,58 @@
+package khata_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestTemplateCaptureEnvironment(t *testing.T) {
+	parent := khata.NewTemplate().SetCaptureEnvironment(true)
+	child := parent.Extend()
+
+	if khata.NewTemplate().New().Environment() != nil {
+		t.Error("NewTemplate() captured the environment by default")
+		return
+	}
+
+	env := child.New().Environment()
+
+	if env == nil || child.IsOverridden("environment") {
+		t.Error("Extend() did not inherit the environment capture")
+		return
+	}
+
+	if env.PID != os.Getpid() || env.Goroutine == 0 || env.Module != "github.com/cmseguin/khata" {
+		t.Error("SetCaptureEnvironment() did not capture the goroutine and process context")
+		return
+	}
+}
+
+func TestKhataCaptureEnvironment(t *testing.T) {
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "42"))
+	k := khata.New("This is an error message").CaptureEnvironment(ctx)
+
+	if k.Environment().Labels["request"] != "42" {
+		t.Error("CaptureEnvironment() did not capture the pprof labels")
+		return
+	}
+
+	decoded, err := khata.FromJSON([]byte(k.ToJSON()))
+	if err != nil || decoded.Environment() == nil || decoded.Environment().Labels["request"] != "42" {
+		t.Error("FromJSON() did not decode the environment")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	k.DebugTo(buf)
+
+	if !strings.Contains(buf.String(), "Environment") || !strings.Contains(buf.String(), "Label request") {
+		t.Error("Debug() did not print the environment")
+		return
+	}
+}
@@ -0,0 +1,106 @@
+package khata
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// Legacy exit code marking the errors that do not exit the program. It is only
+// accepted by Restore, to decode the data encoded before the non-fatal flag:
+// SetExitCode rejects it like any other invalid exit code, use SetNonFatal.
+const NON_FATAL_EXIT_CODE = -1
+
+// Highest exit code, exit statuses are truncated to 8 bits by the operating system
+const MAX_EXIT_CODE = 255
+
+// Added to the signal number to get the exit code of a program terminated by a signal
+const SIGNAL_EXIT_CODE_BASE = 128
+
+// Template of the errors created by FromSignal
+var SignalError = NewTemplate().
+	SetType("Signal").
+	SetMessage("signal received")
+
+// Name of the signal an error originates from, set by FromSignal. The key is
+// namespaced so the properties of the application cannot change the exit status.
+var SignalKey = NewKey[string]("khata.signal")
+
+// Check if the exit code can be passed to os.Exit: between 0 and MAX_EXIT_CODE
+func ValidExitCode(code int) bool {
+	return code >= 0 && code <= MAX_EXIT_CODE
+}
+
+// Returns the exit code of a program terminated by the signal: 128 plus the signal number,
+// like shells report them. Signals without number give DEFAULT_EXIT_CODE.
+func SignalExitCode(sig os.Signal) int {
+	if number, ok := sig.(syscall.Signal); ok {
+		return SIGNAL_EXIT_CODE_BASE + int(number)
+	}
+	return DEFAULT_EXIT_CODE
+}
+
+// Create an error originating from the signal, with the SignalError template.
+// Its exit code is SignalExitCode(sig) and SignalKey holds the name of the signal.
+func FromSignal(sig os.Signal) *Khata {
+	k := SignalError.New("received signal " + sig.String()).
+		SetExitCode(SignalExitCode(sig))
+
+	SignalKey.Set(k, sig.String())
+
+	return k
+}
+
+// Panics if the exit code is not valid. Templates are declared once, so an
+// invalid exit code is a programming error.
+func mustValidExitCode(code int) {
+	if !ValidExitCode(code) {
+		panic(fmt.Sprintf("khata: exit code %d is out of range 0-%d", code, MAX_EXIT_CODE))
+	}
+}
+
+// Returns the status the program exits with when the error is fatal, including
+// the non-fatal errors made fatal by their severity. Errors originating from a
+// signal, created with FromSignal and possibly wrapped, exit with the exit code
+// of the signal.
+func (k *Khata) ExitStatus() int {
+	for _, layer := range k.Chain() {
+		if SignalKey.Has(layer) {
+			return layer.exitCode
+		}
+	}
+	return k.exitCode
+}
+
+// Mark the errors of the template as not exiting the program. The exit code
+// is kept, and still inherited from the parent.
+func (kt *KhataTemplate) SetNonFatal() *KhataTemplate {
+	kt.nonFatal = true
+	kt.overridden |= templateFieldNonFatal
+	return kt
+}
+
+// Returns true if the errors of the template do not exit the program
+func (kt *KhataTemplate) IsNonFatal() bool {
+	return kt.lookup(templateFieldNonFatal).nonFatal
+}
+
+// Mark the error as not exiting the program. The exit code is kept.
+func (k *Khata) SetNonFatal() *Khata {
+	k.nonFatal = true
+	return k
+}
+
+// Returns true if the error does not exit the program
+func (k *Khata) IsNonFatal() bool {
+	return k.nonFatal
+}
+
+// Returns the exit code as it is rendered, like "1 (non-fatal)"
+func (k *Khata) describeExitCode() string {
+	if k.nonFatal {
+		return fmt.Sprintf("%d (non-fatal)", k.exitCode)
+	}
+	return strconv.Itoa(k.exitCode)
+}
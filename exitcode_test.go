@@ -0,0 +1,127 @@
+package khata_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestValidExitCode(t *testing.T) {
+	for _, code := range []int{0, 1, 64, 255} {
+		if !khata.ValidExitCode(code) {
+			t.Error(fmt.Sprintf("ValidExitCode() rejected %d", code))
+			return
+		}
+	}
+
+	for _, code := range []int{khata.NON_FATAL_EXIT_CODE, -2, 256, 1000} {
+		if khata.ValidExitCode(code) {
+			t.Error(fmt.Sprintf("ValidExitCode() accepted %d", code))
+			return
+		}
+	}
+}
+
+func TestKhataTemplateSetExitCodeOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SetExitCode() did not panic with an exit code out of range")
+		}
+	}()
+
+	khata.NewTemplate().SetExitCode(256)
+}
+
+func TestKhataTemplateSetExitCodeNonFatal(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SetExitCode() did not panic with NON_FATAL_EXIT_CODE")
+		}
+	}()
+
+	khata.NewTemplate().SetExitCode(khata.NON_FATAL_EXIT_CODE)
+}
+
+func TestKhataSetExitCodeOutOfRange(t *testing.T) {
+	for _, code := range []int{khata.NON_FATAL_EXIT_CODE, -2, 256} {
+		k := khata.New("This is an error message").SetNonFatal().SetExitCode(code)
+
+		if k.ExitCode() != khata.DEFAULT_EXIT_CODE || !k.IsFatal() {
+			t.Error("SetExitCode() did not replace an invalid exit code with DEFAULT_EXIT_CODE")
+			return
+		}
+	}
+}
+
+func TestKhataSetNonFatal(t *testing.T) {
+	parent := khata.NewTemplate().SetExitCode(64).SetNonFatal()
+	template := parent.Extend()
+
+	if !template.IsNonFatal() || template.ExitCode() != 64 || template.New().IsFatal() || template.New().ExitCode() != 64 {
+		t.Error("SetNonFatal() did not mark the errors of the template as not exiting the program")
+		return
+	}
+
+	if template.SetExitCode(65).IsNonFatal() || !template.New().IsFatal() || !parent.IsNonFatal() {
+		t.Error("SetExitCode() did not make the errors of the template fatal again")
+		return
+	}
+
+	k := khata.New("This is an error message").SetNonFatal()
+
+	if k.IsFatal() || !k.IsNonFatal() || k.ExitCode() != khata.DEFAULT_EXIT_CODE || k.IsExitCode(khata.NON_FATAL_EXIT_CODE) {
+		t.Error("SetNonFatal() did not mark the error as not exiting the program")
+		return
+	}
+}
+
+func TestKhataLegacyNonFatal(t *testing.T) {
+	decoded, err := khata.FromJSON([]byte(`{"error":"Not Found","errorType":"HTTP","errorCode":404,"exitCode":-1,"createdAt":"2023-07-02T04:27:35.000Z","properties":{},"explanations":[]}`))
+	if err != nil || !decoded.IsNonFatal() || decoded.ExitCode() != khata.DEFAULT_EXIT_CODE || decoded.Severity() != khata.SeverityError {
+		t.Error("FromJSON() did not decode the legacy non-fatal exit code")
+		return
+	}
+
+	if strings.Contains(decoded.ToJSON(), `"exitCode":-1`) || !strings.Contains(decoded.ToJSON(), `"nonFatal":true`) {
+		t.Error("ToJSON() did not encode the non-fatal flag separately from the exit code")
+		return
+	}
+}
+
+func TestKhataExitStatus(t *testing.T) {
+	if khata.New("This is an error message").SetExitCode(3).ExitStatus() != 3 {
+		t.Error("ExitStatus() did not use the exit code")
+		return
+	}
+
+	nonFatal := khata.New("This is an error message").SetExitCode(3).SetNonFatal().SetSeverity(khata.SeverityFatal)
+
+	if nonFatal.ExitStatus() != 3 {
+		t.Error("ExitStatus() did not keep the exit code of a non-fatal error made fatal by its severity")
+		return
+	}
+}
+
+func TestFromSignal(t *testing.T) {
+	k := khata.FromSignal(syscall.SIGTERM)
+
+	if k.ExitCode() != 143 || !k.IsInstanceOf(khata.SignalError) || khata.SignalKey.GetOr(k, "") != syscall.SIGTERM.String() {
+		t.Error("FromSignal() did not create a signal error exiting with 128 plus the signal number")
+		return
+	}
+
+	wrapped := khata.Wrap(fmt.Errorf("shutting down: %w", khata.FromSignal(os.Interrupt))).SetExitCode(70)
+	if wrapped.ExitStatus() != 130 {
+		t.Error("ExitStatus() did not use the exit code of the signal the error originates from")
+		return
+	}
+
+	if khata.New("This is an error message").SetExitCode(3).SetProperty("signal", "SIGTERM").ExitStatus() != 3 {
+		t.Error("ExitStatus() used the signal property of the application")
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package khata
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cmseguin/khata/internal/goroutine"
+)
+
+// KhataGoroutineGroup describes goroutines sharing the same state and stack
+type KhataGoroutineGroup struct {
+	// IDs of the goroutines of the group
+	IDs   []uint64
+	State string
+	Trace []KhataTrace
+}
+
+// Attach a dump of the stacks of every goroutine to the error, grouped by identical
+// state and stack. It is useful to diagnose deadlocks, and can be done automatically
+// by HandleKhata for fatal errors with Config.DumpGoroutinesOnFatal.
+func (k *Khata) AttachGoroutineDump() *Khata {
+	k.goroutines = groupGoroutines(goroutine.Dump())
+	return k
+}
+
+// Returns the goroutine dump attached to the error, or nil
+func (k *Khata) Goroutines() []KhataGoroutineGroup {
+	return k.goroutines
+}
+
+// Groups the stacks by state and frames, keeping the order of the first goroutine of each group
+func groupGoroutines(stacks []goroutine.Stack) []KhataGoroutineGroup {
+	groups := []KhataGoroutineGroup{}
+	index := map[string]int{}
+
+	for _, stack := range stacks {
+		key := &strings.Builder{}
+		key.WriteString(stack.State)
+
+		for _, frame := range stack.Frames {
+			fmt.Fprintf(key, "\x00%s\x00%s:%d", frame.Function, frame.File, frame.Line)
+		}
+
+		if i, ok := index[key.String()]; ok {
+			groups[i].IDs = append(groups[i].IDs, stack.ID)
+			continue
+		}
+
+		trace := make([]KhataTrace, len(stack.Frames))
+		for i, frame := range stack.Frames {
+			trace[i] = KhataTrace{file: frame.File, line: frame.Line, functionName: frame.Function}
+		}
+
+		index[key.String()] = len(groups)
+		groups = append(groups, KhataGoroutineGroup{
+			IDs:   []uint64{stack.ID},
+			State: stack.State,
+			Trace: trace,
+		})
+	}
+
+	return groups
+}
+
+func (c *Config) printGoroutines(w io.Writer, pal palette, groups []KhataGoroutineGroup) {
+	for _, group := range groups {
+		ids := make([]string, len(group.IDs))
+		for i, id := range group.IDs {
+			ids[i] = fmt.Sprintf("g%d", id)
+		}
+
+		noun := "goroutines"
+		if len(group.IDs) == 1 {
+			noun = "goroutine"
+		}
+
+		fmt.Fprintf(
+			w,
+			"  %s%d %s%s [%s%s%s]: %s%s%s\n",
+			pal.BoldWhite,
+			len(group.IDs),
+			noun,
+			pal.Reset,
+			pal.Yellow,
+			group.State,
+			pal.Reset,
+			pal.Gray,
+			strings.Join(ids, ", "),
+			pal.Reset,
+		)
+
+		for _, trace := range c.frames(group.Trace) {
+			fmt.Fprintf(
+				w,
+				"    %s%s%s:%s%d%s (%s%s%s)\n",
+				pal.UnderlineGray,
+				c.trimPath(trace.file),
+				pal.Reset,
+				pal.Green,
+				trace.line,
+				pal.Reset,
+				pal.Cyan,
+				c.trimFunc(trace.functionName),
+				pal.Reset,
+			)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package khata_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func blockedWorker(ready *sync.WaitGroup, release chan struct{}) {
+	ready.Done()
+	<-release
+}
+
+func findWorkerGroup(groups []khata.KhataGoroutineGroup) *khata.KhataGoroutineGroup {
+	for i, group := range groups {
+		for _, frame := range group.Trace {
+			if strings.HasSuffix(frame.FunctionName(), "khata_test.blockedWorker") {
+				return &groups[i]
+			}
+		}
+	}
+	return nil
+}
+
+func TestKhataAttachGoroutineDump(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	ready := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		ready.Add(1)
+		go blockedWorker(ready, release)
+	}
+	ready.Wait()
+
+	k := khata.New("This is an error message").AttachGoroutineDump()
+	group := findWorkerGroup(k.Goroutines())
+
+	if group == nil || len(group.IDs) != 3 || group.State != "chan receive" {
+		t.Error("AttachGoroutineDump() did not group the identical goroutines")
+		return
+	}
+
+	decoded, err := khata.FromJSON([]byte(k.ToJSON()))
+	if err != nil || findWorkerGroup(decoded.Goroutines()) == nil {
+		t.Error("FromJSON() did not decode the goroutine dump")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	k.DebugTo(buf)
+
+	if !strings.Contains(buf.String(), "3 goroutines") {
+		t.Error("Debug() did not print the goroutine dump")
+		return
+	}
+}
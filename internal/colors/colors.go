@@ -47,3 +47,14 @@ func init() {
 		White = ""
 	}
 }
+
+// Disable removes every color, for outputs that are not terminals
+func Disable() {
+	for _, color := range []*string{
+		&Reset, &Red, &Green, &Yellow, &Blue, &Purple, &Cyan, &Gray, &White, &Black,
+		&BoldRed, &BoldGreen, &BoldYellow, &BoldBlue, &BoldPurple, &BoldCyan, &BoldGray, &BoldWhite, &BoldBlack,
+		&UnderlineRed, &UnderlineGreen, &UnderlineYellow, &UnderlineBlue, &UnderlinePurple, &UnderlineCyan, &UnderlineGray, &UnderlineWhite, &UnderlineBlack,
+	} {
+		*color = ""
+	}
+}
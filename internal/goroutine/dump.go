@@ -0,0 +1,118 @@
+package goroutine
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Frame of a goroutine stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Stack of a goroutine, parsed from a runtime dump
+type Stack struct {
+	ID     uint64
+	State  string
+	Frames []Frame
+}
+
+// Returns the stacks of every goroutine
+func Dump() []Stack {
+	buf := make([]byte, 64*1024)
+	n := runtime.Stack(buf, true)
+
+	// Grow the buffer until it holds the whole dump
+	for n == len(buf) {
+		buf = make([]byte, len(buf)*2)
+		n = runtime.Stack(buf, true)
+	}
+
+	return Parse(buf[:n])
+}
+
+// Parses a dump in the format of runtime.Stack. Unknown lines are ignored.
+func Parse(dump []byte) []Stack {
+	stacks := []Stack{}
+	var current *Stack
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(make([]byte, 64*1024), len(dump)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "goroutine "):
+			stacks = append(stacks, parseHeader(line))
+			current = &stacks[len(stacks)-1]
+
+		case current == nil || line == "":
+			continue
+
+		case strings.HasPrefix(line, "\t"):
+			// Location of the last function
+			if n := len(current.Frames); n != 0 && current.Frames[n-1].File == "" {
+				current.Frames[n-1].File, current.Frames[n-1].Line = parseLocation(line)
+			}
+
+		case strings.HasPrefix(line, "..."):
+			continue
+
+		default:
+			current.Frames = append(current.Frames, Frame{Function: parseFunction(line)})
+		}
+	}
+
+	return stacks
+}
+
+// Parses a header like "goroutine 12 [chan receive, 3 minutes]:"
+func parseHeader(line string) Stack {
+	stack := Stack{}
+	rest := strings.TrimPrefix(line, "goroutine ")
+
+	if id, state, ok := strings.Cut(rest, " ["); ok {
+		stack.ID, _ = strconv.ParseUint(id, 10, 64)
+		state = strings.TrimSuffix(state, "]:")
+		stack.State, _, _ = strings.Cut(state, ",")
+	}
+
+	return stack
+}
+
+// Parses a function line like "main.worker(0xc000012345)" or "created by main.main in goroutine 1"
+func parseFunction(line string) string {
+	if created, ok := strings.CutPrefix(line, "created by "); ok {
+		function, _, _ := strings.Cut(created, " in goroutine ")
+		return function
+	}
+
+	if i := strings.LastIndex(line, "("); i > 0 {
+		return line[:i]
+	}
+
+	return line
+}
+
+// Parses a location like "\t/src/main.go:12 +0x1d"
+func parseLocation(line string) (string, int) {
+	location := strings.TrimSpace(line)
+
+	if i := strings.LastIndex(location, " +0x"); i >= 0 {
+		location = location[:i]
+	}
+
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return location, 0
+	}
+
+	n, _ := strconv.Atoi(location[i+1:])
+	return location[:i], n
+}
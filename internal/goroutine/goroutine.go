@@ -0,0 +1,34 @@
+package goroutine
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+var prefix = []byte("goroutine ")
+
+// Returns the ID of the calling goroutine, or 0 if it cannot be determined.
+// The runtime does not expose it, so it is parsed from the header of the current stack.
+func ID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+
+	if !bytes.HasPrefix(header, prefix) {
+		return 0
+	}
+
+	header = header[len(prefix):]
+
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+
+	id, err := strconv.ParseUint(string(header), 10, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
@@ -0,0 +1,428 @@
+package khata
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version of the JSON representation produced by ToJSON, WriteJSON and JSONEncoder
+const JSON_SCHEMA_VERSION = 1
+
+// JSON Schema describing the JSON representation of khata errors
+//
+//go:embed khata.schema.json
+var JSONSchema string
+
+// Layout of the timestamps produced before the JSON representation was versioned
+const legacyJSONTimeLayout = "2006-01-02T15:04:05.000Z-0700"
+
+type jsonError struct {
+	Version      int                        `json:"version,omitempty"`
+	Error        string                     `json:"error"`
+	ErrorType    string                     `json:"errorType"`
+	ErrorCode    int                        `json:"errorCode"`
+	CodeName     string                     `json:"codeName,omitempty"`
+	ExitCode     int                        `json:"exitCode"`
+	NonFatal     bool                       `json:"nonFatal,omitempty"`
+	Severity     Severity                   `json:"severity"`
+	CreatedAt    string                     `json:"createdAt"`
+	HandledAt    string                     `json:"handledAt,omitempty"`
+	Properties   map[string]json.RawMessage `json:"properties"`
+	Explanations []jsonExplanation          `json:"explanations"`
+	Environment  *KhataEnvironment          `json:"environment,omitempty"`
+	Trace        []jsonFrame                `json:"trace,omitempty"`
+	Goroutines   []jsonGoroutineGroup       `json:"goroutines,omitempty"`
+	Cause        *jsonError                 `json:"cause,omitempty"`
+}
+
+type jsonExplanation struct {
+	Message      string                     `json:"message"`
+	File         string                     `json:"file"`
+	Line         int                        `json:"line"`
+	FunctionName string                     `json:"functionName"`
+	Fields       map[string]json.RawMessage `json:"fields,omitempty"`
+	ElapsedMs    float64                    `json:"elapsedMs"`
+	Goroutine    uint64                     `json:"goroutine"`
+}
+
+type jsonFrame struct {
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	FunctionName string `json:"functionName"`
+	Folded       int    `json:"folded,omitempty"`
+	OwnCode      bool   `json:"ownCode,omitempty"`
+}
+
+type jsonGoroutineGroup struct {
+	IDs   []uint64    `json:"ids"`
+	State string      `json:"state"`
+	Trace []jsonFrame `json:"trace"`
+}
+
+type jsonOptions struct {
+	indent         string
+	omitTrace      bool
+	maxTraceDepth  int
+	stringFallback bool
+	creationTrace  bool
+}
+
+// Option used to configure the JSON representation of errors
+type JSONOption func(*jsonOptions)
+
+// Indent the JSON output with the given string
+func JSONIndent(indent string) JSONOption {
+	return func(options *jsonOptions) {
+		options.indent = indent
+	}
+}
+
+// Leave the trace out of the JSON output
+func JSONWithoutTrace() JSONOption {
+	return func(options *jsonOptions) {
+		options.omitTrace = true
+	}
+}
+
+// Write the trace captured when the error was created, see CreationTrace,
+// instead of the trace collected at the time of writing
+func JSONCreationTrace() JSONOption {
+	return func(options *jsonOptions) {
+		options.creationTrace = true
+	}
+}
+
+// Only keep the given number of frames of the trace. Zero or less keeps every frame.
+func JSONMaxTraceDepth(depth int) JSONOption {
+	return func(options *jsonOptions) {
+		options.maxTraceDepth = depth
+	}
+}
+
+// Serialize the property and field values that cannot be marshaled, like
+// channels and functions, as a string formatted with %v instead of failing.
+func JSONStringFallback() JSONOption {
+	return func(options *jsonOptions) {
+		options.stringFallback = true
+	}
+}
+
+// JSONEncoder writes khata errors as JSON documents, one per line.
+type JSONEncoder struct {
+	w       io.Writer
+	options jsonOptions
+}
+
+// Create a JSON encoder writing on w
+func NewJSONEncoder(w io.Writer, options ...JSONOption) *JSONEncoder {
+	encoder := &JSONEncoder{w: w}
+
+	for _, option := range options {
+		option(&encoder.options)
+	}
+
+	return encoder
+}
+
+// Write the error as a JSON document followed by a newline.
+// The handledAt and trace will be generated at the time of calling this method unless the error was marked as handled.
+func (e *JSONEncoder) Encode(k *Khata) error {
+	document, err := k.toJSONError(&e.options)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(e.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", e.options.indent)
+
+	return encoder.Encode(document)
+}
+
+// Write the JSON representation of the error on w. Unlike ToJSON, errors are returned,
+// including the ones caused by property values that cannot be marshaled.
+func (k *Khata) WriteJSON(w io.Writer, options ...JSONOption) error {
+	return NewJSONEncoder(w, options...).Encode(k)
+}
+
+// Returns a JSON string representation of the error.
+// This is useful to log or store the error.
+// The handledAt and trace will be generated at the time of calling this method unless the error was marked as handled.
+// Inner khata errors of the wrap chain are nested under "cause".
+// Values that cannot be marshaled are serialized as strings, see JSONStringFallback.
+func (k *Khata) ToJSON() string {
+	var buf bytes.Buffer
+
+	if err := k.WriteJSON(&buf, JSONStringFallback()); err != nil {
+		return ""
+	}
+
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+func (k *Khata) toJSONError(options *jsonOptions) (*jsonError, error) {
+	document, err := k.toJSONLayer(options)
+	if err != nil {
+		return nil, err
+	}
+
+	document.Version = JSON_SCHEMA_VERSION
+	document.HandledAt = formatJSONTime(k.handledTime())
+
+	for _, group := range k.goroutines {
+		document.Goroutines = append(document.Goroutines, jsonGoroutineGroup{
+			IDs:   group.IDs,
+			State: group.State,
+			Trace: toJSONFrames(group.Trace, options),
+		})
+	}
+
+	if options.omitTrace {
+		return document, nil
+	}
+
+	if options.creationTrace {
+		document.Trace = toJSONFrames(k.CreationTrace(), options)
+	} else {
+		document.Trace = toJSONFrames(k.Trace(), options)
+	}
+
+	return document, nil
+}
+
+// Returns the frames of the trace after the trace filters of the configuration and the maximum depth
+func toJSONFrames(trace []KhataTrace, options *jsonOptions) []jsonFrame {
+	trace = currentConfig().frames(trace)
+	if options.maxTraceDepth > 0 && len(trace) > options.maxTraceDepth {
+		trace = trace[:options.maxTraceDepth]
+	}
+
+	frames := make([]jsonFrame, len(trace))
+	for i, t := range trace {
+		frames[i] = jsonFrame{
+			File:         t.file,
+			Line:         t.line,
+			FunctionName: t.functionName,
+			Folded:       t.folded,
+			OwnCode:      t.IsOwnCode(),
+		}
+	}
+
+	return frames
+}
+
+// Returns the layer specific fields of the error, with its causes nested
+func (k *Khata) toJSONLayer(options *jsonOptions) (*jsonError, error) {
+	properties, err := marshalValues(k.SerializedProperties(), options, "property")
+	if err != nil {
+		return nil, err
+	}
+
+	document := &jsonError{
+		Error:        k.Err.Error(),
+		ErrorType:    k.errorType,
+		ErrorCode:    k.errorCode,
+		CodeName:     k.codeName,
+		ExitCode:     k.exitCode,
+		NonFatal:     k.nonFatal,
+		Severity:     k.Severity(),
+		CreatedAt:    formatJSONTime(k.createdAt),
+		Properties:   properties,
+		Explanations: make([]jsonExplanation, len(k.explanationStack)),
+		Environment:  k.environment,
+	}
+
+	for i, e := range k.explanationStack {
+		fields, err := marshalValues(e.Fields, options, "explanation field")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fields) == 0 {
+			fields = nil
+		}
+
+		document.Explanations[i] = jsonExplanation{
+			Message:      e.Message,
+			File:         e.File,
+			Line:         e.Line,
+			FunctionName: e.FunctionName,
+			Fields:       fields,
+			ElapsedMs:    float64(e.Elapsed.Microseconds()) / 1000,
+			Goroutine:    e.Goroutine,
+		}
+	}
+
+	if cause := k.Cause(); cause != nil {
+		if document.Cause, err = cause.toJSONLayer(options); err != nil {
+			return nil, err
+		}
+	}
+
+	return document, nil
+}
+
+// Marshal every value separately so a single invalid value can fall back to a string
+func marshalValues(values map[string]interface{}, options *jsonOptions, kind string) (map[string]json.RawMessage, error) {
+	marshaled := make(map[string]json.RawMessage, len(values))
+
+	for key, value := range values {
+		raw, err := json.Marshal(value)
+
+		if err != nil && options.stringFallback {
+			raw, err = json.Marshal(fmt.Sprintf("%v", value))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("khata: cannot marshal %s %q: %w", kind, key, err)
+		}
+
+		marshaled[key] = raw
+	}
+
+	return marshaled, nil
+}
+
+func formatJSONTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseJSONTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t, err = time.Parse(legacyJSONTimeLayout, value)
+	}
+	return t.UTC(), err
+}
+
+type jsonDecodedError struct {
+	Version      int                    `json:"version"`
+	Error        string                 `json:"error"`
+	ErrorType    string                 `json:"errorType"`
+	ErrorCode    int                    `json:"errorCode"`
+	CodeName     string                 `json:"codeName"`
+	ExitCode     int                    `json:"exitCode"`
+	NonFatal     bool                   `json:"nonFatal"`
+	Severity     string                 `json:"severity"`
+	CreatedAt    string                 `json:"createdAt"`
+	HandledAt    string                 `json:"handledAt"`
+	Properties   map[string]interface{} `json:"properties"`
+	Explanations []struct {
+		Message      string                 `json:"message"`
+		File         string                 `json:"file"`
+		Line         int                    `json:"line"`
+		FunctionName string                 `json:"functionName"`
+		Fields       map[string]interface{} `json:"fields"`
+		ElapsedMs    float64                `json:"elapsedMs"`
+		Goroutine    uint64                 `json:"goroutine"`
+	} `json:"explanations"`
+	Environment *KhataEnvironment    `json:"environment"`
+	Trace       []jsonFrame          `json:"trace"`
+	Goroutines  []jsonGoroutineGroup `json:"goroutines"`
+	Cause       *jsonDecodedError    `json:"cause"`
+}
+
+// Decodes a khata error serialized with ToJSON. The decoded error keeps the
+// serialized trace and handling time instead of computing them again.
+// Returns an error if the data is not a JSON object describing a khata error,
+// or if it was produced by a newer version of the JSON representation.
+func FromJSON(data []byte) (*Khata, error) {
+	var decoded jsonDecodedError
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	if decoded.ErrorType == "" {
+		return nil, errors.New("not a khata error: missing errorType")
+	}
+
+	if decoded.Version > JSON_SCHEMA_VERSION {
+		return nil, fmt.Errorf("unsupported khata JSON version %d", decoded.Version)
+	}
+
+	record, err := decoded.toRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	return Restore(record), nil
+}
+
+func (j *jsonDecodedError) toRecord() (*KhataRecord, error) {
+	record := &KhataRecord{
+		Message:     j.Error,
+		Type:        j.ErrorType,
+		Code:        j.ErrorCode,
+		CodeName:    j.CodeName,
+		ExitCode:    j.ExitCode,
+		NonFatal:    j.NonFatal,
+		Properties:  j.Properties,
+		Environment: j.Environment,
+	}
+
+	if j.Cause != nil {
+		cause, err := j.Cause.toRecord()
+		if err != nil {
+			return nil, err
+		}
+		record.Cause = cause
+	}
+
+	if severity, ok := ParseSeverity(j.Severity); ok {
+		record.Severity = severity
+	}
+
+	if j.CreatedAt != "" {
+		createdAt, err := parseJSONTime(j.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		record.CreatedAt = createdAt
+	}
+
+	if j.HandledAt != "" {
+		handledAt, err := parseJSONTime(j.HandledAt)
+		if err != nil {
+			return nil, err
+		}
+		record.HandledAt = handledAt
+	}
+
+	for _, e := range j.Explanations {
+		record.Explanations = append(record.Explanations, KhataExplanation{
+			Message:      e.Message,
+			File:         e.File,
+			Line:         e.Line,
+			FunctionName: e.FunctionName,
+			Fields:       e.Fields,
+			Elapsed:      time.Duration(e.ElapsedMs * float64(time.Millisecond)),
+			Goroutine:    e.Goroutine,
+		})
+	}
+
+	record.Trace = fromJSONFrames(j.Trace)
+
+	for _, group := range j.Goroutines {
+		record.Goroutines = append(record.Goroutines, KhataGoroutineGroup{
+			IDs:   group.IDs,
+			State: group.State,
+			Trace: fromJSONFrames(group.Trace),
+		})
+	}
+
+	return record, nil
+}
+
+func fromJSONFrames(frames []jsonFrame) []KhataTrace {
+	var trace []KhataTrace
+	for _, t := range frames {
+		trace = append(trace, NewFoldedKhataTrace(t.File, t.Line, t.FunctionName, t.Folded))
+	}
+	return trace
+}
@@ -0,0 +1,159 @@
+package khata_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestKhataWriteJSON(t *testing.T) {
+	k := khata.New("This is an error message").SetProperty("channel", make(chan int))
+
+	var buf bytes.Buffer
+
+	if err := k.WriteJSON(&buf); err == nil || !strings.Contains(err.Error(), `"channel"`) {
+		t.Error("WriteJSON() did not return an error for a property that cannot be marshaled")
+		return
+	}
+
+	buf.Reset()
+
+	if err := k.WriteJSON(&buf, khata.JSONStringFallback(), khata.JSONWithoutTrace(), khata.JSONIndent("  ")); err != nil {
+		t.Error("WriteJSON() returned an error with the string fallback")
+		return
+	}
+
+	var output map[string]interface{}
+
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Error("WriteJSON() did not write valid JSON")
+		return
+	}
+
+	if _, ok := output["trace"]; ok {
+		t.Error("WriteJSON() did not leave the trace out")
+		return
+	}
+
+	if output["version"] != float64(khata.JSON_SCHEMA_VERSION) {
+		t.Error("WriteJSON() did not write the version")
+		return
+	}
+
+	if value, _ := output["properties"].(map[string]interface{})["channel"].(string); !strings.HasPrefix(value, "0x") {
+		t.Error("WriteJSON() did not format the property value as a string")
+		return
+	}
+
+	if !strings.Contains(buf.String(), "\n  \"") {
+		t.Error("WriteJSON() did not indent the output")
+		return
+	}
+
+	if k.ToJSON() == "" {
+		t.Error("ToJSON() did not fall back to strings")
+		return
+	}
+}
+
+func TestKhataJSONTimestamps(t *testing.T) {
+	createdAt := time.Date(2023, 7, 2, 4, 27, 35, 500000000, time.UTC)
+	khata.SetClock(khata.ClockFunc(func() time.Time { return createdAt }))
+	defer khata.SetClock(nil)
+
+	var output struct {
+		CreatedAt string        `json:"createdAt"`
+		Trace     []interface{} `json:"trace"`
+	}
+
+	var buf bytes.Buffer
+	khata.New("This is an error message").WriteJSON(&buf, khata.JSONMaxTraceDepth(1))
+
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Error("WriteJSON() did not write valid JSON")
+		return
+	}
+
+	if output.CreatedAt != "2023-07-02T04:27:35.5Z" {
+		t.Error("WriteJSON() did not use RFC 3339 timestamps")
+		return
+	}
+
+	if len(output.Trace) != 1 {
+		t.Error("WriteJSON() did not limit the trace depth")
+		return
+	}
+}
+
+func TestKhataFromLegacyJSON(t *testing.T) {
+	legacy := `{"error":"Not Found","errorType":"HTTP","errorCode":404,"exitCode":1,"createdAt":"2023-07-02T04:27:35.000Z+0000","properties":{},"explanations":[],"trace":[]}`
+
+	k, err := khata.FromJSON([]byte(legacy))
+
+	if err != nil || !k.CreatedAt().Equal(time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)) {
+		t.Error("FromJSON() did not decode the legacy representation")
+		return
+	}
+
+	if _, err := khata.FromJSON([]byte(`{"version":99,"errorType":"HTTP"}`)); err == nil {
+		t.Error("FromJSON() accepted an unsupported version")
+		return
+	}
+}
+
+func TestKhataFromJSONWrapChain(t *testing.T) {
+	cause := khata.New("connection refused").SetType("Database")
+	k := khata.Wrap(fmt.Errorf("loading user: %w", cause)).SetType("Repository")
+
+	decoded, err := khata.FromJSON([]byte(k.ToJSON()))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if decoded.Error() != "loading user: connection refused" || decoded.Type() != "Repository" {
+		t.Error("FromJSON() did not keep the message of the outer error")
+		return
+	}
+
+	restoredCause := decoded.Cause()
+	if restoredCause == nil || restoredCause.Error() != "connection refused" || restoredCause.Type() != "Database" {
+		t.Error("FromJSON() did not restore the cause")
+		return
+	}
+
+	again, err := khata.FromJSON([]byte(decoded.ToJSON()))
+	if err != nil || again.Error() != decoded.Error() || again.Cause().Error() != restoredCause.Error() {
+		t.Error("FromJSON() did not round trip the wrap chain")
+		return
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Defs struct {
+			Layer struct {
+				Required []string `json:"required"`
+			} `json:"layer"`
+		} `json:"$defs"`
+	}
+
+	if err := json.Unmarshal([]byte(khata.JSONSchema), &schema); err != nil {
+		t.Error("JSONSchema is not valid JSON")
+		return
+	}
+
+	var output map[string]interface{}
+	json.Unmarshal([]byte(khata.New("This is an error message").ToJSON()), &output)
+
+	for _, field := range schema.Defs.Layer.Required {
+		if _, ok := output[field]; !ok {
+			t.Errorf("ToJSON() did not write the required field %q", field)
+		}
+	}
+}
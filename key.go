@@ -0,0 +1,129 @@
+package khata
+
+// The value shown in place of redacted properties when rendering an error
+const REDACTED_VALUE = "[REDACTED]"
+
+// Metadata attached to a property when it is set through a typed Key
+type propertyMeta struct {
+	jsonName string
+	redacted bool
+}
+
+// Option used to configure a typed Key
+type KeyOption func(*propertyMeta)
+
+// Hide the value of the property in Debug() and ToJSON(). The value is still
+// available through GetProperty and Key.Get.
+func Redacted() KeyOption {
+	return func(meta *propertyMeta) {
+		meta.redacted = true
+	}
+}
+
+// Use a different name for the property in the ToJSON() output
+func JSONName(name string) KeyOption {
+	return func(meta *propertyMeta) {
+		meta.jsonName = name
+	}
+}
+
+// PropertyHolder is implemented by both Khata and KhataTemplate so typed keys
+// can be used on either of them.
+type PropertyHolder interface {
+	GetProperty(key string) interface{}
+	HasProperty(key string) bool
+	setTypedProperty(key string, value interface{}, meta propertyMeta)
+	removeTypedProperty(key string)
+}
+
+// Key is a typed property key. It is meant to be declared once, usually as a
+// package variable, and used to set and read properties without type assertions.
+//
+//	var UserID = khata.NewKey[int]("userID")
+//
+//	UserID.Set(k, 42)
+//	id, ok := UserID.Get(k)
+type Key[T any] struct {
+	name string
+	meta propertyMeta
+}
+
+// Create a new typed property key
+func NewKey[T any](name string, options ...KeyOption) Key[T] {
+	key := Key[T]{name: name}
+
+	for _, option := range options {
+		option(&key.meta)
+	}
+
+	return key
+}
+
+// Returns the name the property is stored under
+func (key Key[T]) Name() string {
+	return key.name
+}
+
+// Returns the name the property is serialized under in ToJSON()
+func (key Key[T]) JSONName() string {
+	if key.meta.jsonName != "" {
+		return key.meta.jsonName
+	}
+	return key.name
+}
+
+// Returns true if the property is redacted when rendered
+func (key Key[T]) IsRedacted() bool {
+	return key.meta.redacted
+}
+
+// Set the property on the error or template
+func (key Key[T]) Set(holder PropertyHolder, value T) {
+	holder.setTypedProperty(key.name, value, key.meta)
+}
+
+// Returns the value of the property and true if it is set with the key's type
+func (key Key[T]) Get(holder PropertyHolder) (T, bool) {
+	value, ok := holder.GetProperty(key.name).(T)
+	return value, ok
+}
+
+// Returns the value of the property, or the given fallback if it is not set
+func (key Key[T]) GetOr(holder PropertyHolder, fallback T) T {
+	if value, ok := key.Get(holder); ok {
+		return value
+	}
+	return fallback
+}
+
+// Check if the property is set on the error or template
+func (key Key[T]) Has(holder PropertyHolder) bool {
+	return holder.HasProperty(key.name)
+}
+
+// Remove the property from the error or template
+func (key Key[T]) Remove(holder PropertyHolder) {
+	holder.removeTypedProperty(key.name)
+}
+
+// Returns the properties as they should be rendered, with the key metadata applied.
+// When useJSONNames is true, the keys are renamed with their JSON name.
+func renderProperties(properties map[string]interface{}, meta map[string]propertyMeta, useJSONNames bool) map[string]interface{} {
+	rendered := make(map[string]interface{}, len(properties))
+
+	for key, value := range properties {
+		m, ok := meta[key]
+
+		if ok && m.redacted {
+			value = REDACTED_VALUE
+		}
+
+		if ok && useJSONNames && m.jsonName != "" {
+			key = m.jsonName
+		}
+
+		rendered[key] = value
+	}
+
+	return rendered
+}
@@ -0,0 +1,94 @@
+package khata_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+var userIDKey = khata.NewKey[int]("userID")
+var tokenKey = khata.NewKey[string]("token", khata.Redacted(), khata.JSONName("authToken"))
+
+func TestKeyOnKhata(t *testing.T) {
+	k := khata.New("This is an error message")
+
+	if _, ok := userIDKey.Get(k); ok {
+		t.Error("Get() returned a value for a property that does not exist")
+		return
+	}
+
+	userIDKey.Set(k, 42)
+
+	if id, ok := userIDKey.Get(k); !ok || id != 42 {
+		t.Error("Get() did not return the value set with Set()")
+		return
+	}
+
+	if k.GetProperty("userID") != 42 {
+		t.Error("Set() did not store the property under the key name")
+		return
+	}
+
+	k.SetProperty("userID", "not an int")
+
+	if _, ok := userIDKey.Get(k); ok {
+		t.Error("Get() returned a value with the wrong type")
+		return
+	}
+
+	if userIDKey.GetOr(k, 7) != 7 {
+		t.Error("GetOr() did not return the fallback")
+		return
+	}
+
+	userIDKey.Remove(k)
+
+	if userIDKey.Has(k) {
+		t.Error("Remove() did not remove the property")
+		return
+	}
+}
+
+func TestKeyOnTemplate(t *testing.T) {
+	template := khata.NewTemplate()
+	userIDKey.Set(template, 12)
+
+	k := template.New()
+
+	if id, _ := userIDKey.Get(k); id != 12 {
+		t.Error("New() did not copy the typed property from the template")
+		return
+	}
+
+	userIDKey.Set(k, 13)
+
+	if id, _ := userIDKey.Get(template); id != 12 {
+		t.Error("Set() on the error modified the template")
+		return
+	}
+}
+
+func TestKeyMetadataInJSON(t *testing.T) {
+	k := khata.New("This is an error message")
+	tokenKey.Set(k, "secret")
+
+	var output struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+
+	if err := json.Unmarshal([]byte(k.ToJSON()), &output); err != nil {
+		t.Error("ToJSON() did not return valid JSON")
+		return
+	}
+
+	if output.Properties["authToken"] != khata.REDACTED_VALUE {
+		t.Error("ToJSON() did not rename and redact the property")
+		return
+	}
+
+	if value, _ := tokenKey.Get(k); value != "secret" {
+		t.Error("Get() did not return the unredacted value")
+		return
+	}
+}
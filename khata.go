@@ -1,15 +1,19 @@
 package khata
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/cmseguin/khata/internal/colors"
+	"github.com/cmseguin/khata/internal/goroutine"
 )
 
 const (
@@ -23,6 +27,8 @@ type KhataTrace struct {
 	file         string
 	line         int
 	functionName string
+	// Number of frames folded into this one by the trace filters
+	folded int
 }
 
 func (kt *KhataTrace) File() string {
@@ -37,20 +43,83 @@ func (kt *KhataTrace) FunctionName() string {
 	return kt.functionName
 }
 
-type KhataExplanation struct {
-	Message      string `json:"message"`
-	File         string `json:"file"`
-	Line         int    `json:"line"`
-	FunctionName string `json:"functionName"`
+// Returns the number of frames folded into this one, from recursive calls or the standard library
+func (kt *KhataTrace) Folded() int {
+	return kt.folded
 }
 
+// Returns true if the frame belongs to the main module of the program, false for its dependencies
+func (kt *KhataTrace) IsOwnCode() bool {
+	module := mainModule()
+	if module == "" {
+		return false
+	}
+
+	pkg := funcPackage(kt.functionName)
+	return pkg == module || strings.HasPrefix(pkg, module+"/")
+}
+
+type KhataExplanation struct {
+	Message      string                 `json:"message"`
+	File         string                 `json:"file"`
+	Line         int                    `json:"line"`
+	FunctionName string                 `json:"functionName"`
+	Fields       map[string]interface{} `json:"fields,omitempty"`
+	// Time elapsed between the creation of the error and the explanation
+	Elapsed time.Duration `json:"elapsed"`
+	// ID of the goroutine that added the explanation
+	Goroutine uint64 `json:"goroutine"`
+}
+
+// Fields of a template that can be overridden locally or inherited from the parent
+const (
+	templateFieldMessage = 1 << iota
+	templateFieldCode
+	templateFieldType
+	templateFieldExitCode
+	templateFieldSeverity
+	templateFieldEnvironment
+	templateFieldCodeName
+	templateFieldNonFatal
+
+	templateFieldAll = templateFieldMessage | templateFieldCode | templateFieldType | templateFieldExitCode | templateFieldSeverity | templateFieldEnvironment | templateFieldCodeName | templateFieldNonFatal
+)
+
+var templateFieldNames = map[string]int{
+	"message":     templateFieldMessage,
+	"code":        templateFieldCode,
+	"type":        templateFieldType,
+	"exitCode":    templateFieldExitCode,
+	"severity":    templateFieldSeverity,
+	"environment": templateFieldEnvironment,
+	"codeName":    templateFieldCodeName,
+	"nonFatal":    templateFieldNonFatal,
+}
+
+// KhataTemplate describes the context shared by a family of errors.
+//
+// Templates created with Extend() inherit from their parent at read time: a
+// field that was never set on the child resolves through the parent chain, so
+// later changes on the parent are visible from its children. Fields set on the
+// child are overridden and stay local. Properties follow the same rule, key by key.
 type KhataTemplate struct {
-	message    string
-	errorCode  int
-	errorType  string
-	exitCode   int
-	properties map[string]interface{}
-	parent     *KhataTemplate
+	message            string
+	errorCode          int
+	codeName           string
+	errorType          string
+	exitCode           int
+	nonFatal           bool
+	severity           Severity
+	captureEnvironment bool
+	overridden         int
+	properties         map[string]interface{}
+	meta               map[string]propertyMeta
+	removed            map[string]bool
+	clock              Clock
+	parent             *KhataTemplate
+	// Guards children, templates can be extended concurrently
+	childrenMutex sync.Mutex
+	children      []*KhataTemplate
 }
 
 // Create a new khata error with the template
@@ -65,7 +134,7 @@ func (kt *KhataTemplate) New(message ...string) *Khata {
 	}
 
 	if inputMessage == "" {
-		inputMessage = kt.message
+		inputMessage = kt.Message()
 	}
 
 	return kt.Wrap(errors.New(inputMessage))
@@ -73,118 +142,298 @@ func (kt *KhataTemplate) New(message ...string) *Khata {
 
 // Wraps an error with a Khata object while using the template
 func (kt *KhataTemplate) Wrap(err error) *Khata {
-	return &Khata{
+	properties, meta := kt.resolveProperties()
+
+	k := &Khata{
 		Err:              err,
-		createdAt:        time.Now().UTC(),
-		errorCode:        kt.errorCode,
-		errorType:        kt.errorType,
-		exitCode:         kt.exitCode,
+		createdAt:        now(kt.Clock()),
+		clock:            kt.Clock(),
+		errorCode:        kt.Code(),
+		codeName:         kt.CodeName(),
+		errorType:        kt.Type(),
+		exitCode:         kt.ExitCode(),
+		nonFatal:         kt.IsNonFatal(),
+		severity:         kt.lookup(templateFieldSeverity).severity,
 		explanationStack: []KhataExplanation{},
-		properties:       kt.properties,
+		properties:       properties,
+		meta:             meta,
 		traceStack:       []KhataTrace{},
 		template:         kt,
 	}
+
+	k.captureCreation()
+
+	if kt.CapturesEnvironment() {
+		k.environment = captureEnvironment(nil)
+	}
+
+	notifyCreated(k)
+
+	return k
 }
 
 func (kt *KhataTemplate) Apply(k *Khata) *Khata {
 	k.errorCode = kt.Code()
+	k.codeName = kt.CodeName()
 	k.errorType = kt.Type()
 	k.exitCode = kt.ExitCode()
+	k.nonFatal = kt.IsNonFatal()
+	k.severity = kt.lookup(templateFieldSeverity).severity
 
-	for key, value := range kt.properties {
+	properties, meta := kt.resolveProperties()
+
+	for key, value := range properties {
 		k.properties[key] = value
 	}
 
+	for key, meta := range meta {
+		k.meta[key] = meta
+	}
+
 	k.template = kt
 
 	return k
 }
 
-// Allows to extend or copy the template
+// Allows to extend the template. The returned template inherits every field
+// and property from this one until they are overridden on it.
 func (kt *KhataTemplate) Extend() *KhataTemplate {
-	return &KhataTemplate{
-		errorCode:  kt.errorCode,
-		errorType:  kt.errorType,
-		exitCode:   kt.exitCode,
-		properties: kt.properties,
-		message:    kt.message,
+	child := &KhataTemplate{
+		properties: map[string]interface{}{},
+		meta:       map[string]propertyMeta{},
+		removed:    map[string]bool{},
 		parent:     kt,
 	}
+
+	kt.childrenMutex.Lock()
+	kt.children = append(kt.children, child)
+	kt.childrenMutex.Unlock()
+
+	return child
 }
 
 // Returns the message associated with the template
 func (kt *KhataTemplate) Message() string {
-	return kt.message
+	return kt.lookup(templateFieldMessage).message
 }
 
 // Sets the message associated with the template
 func (kt *KhataTemplate) SetMessage(message string) *KhataTemplate {
 	kt.message = message
+	kt.overridden |= templateFieldMessage
 	return kt
 }
 
 // Returns the error code associated with the template
 func (kt *KhataTemplate) Code() int {
-	return kt.errorCode
+	return kt.lookup(templateFieldCode).errorCode
 }
 
 // Sets the error code associated with the template
 func (kt *KhataTemplate) SetCode(code int) *KhataTemplate {
 	kt.errorCode = code
+	kt.overridden |= templateFieldCode
 	return kt
 }
 
+// Returns the code name associated with the template: the code names set on
+// the template and its parents, joined from the root, like AUTH.TOKEN.EXPIRED
+func (kt *KhataTemplate) CodeName() string {
+	name := ""
+	if kt.parent != nil {
+		name = kt.parent.CodeName()
+	}
+
+	if kt.overridden&templateFieldCodeName != 0 {
+		name = joinCodeNames(name, kt.codeName)
+	}
+
+	return name
+}
+
+// Sets the code name associated with the template. It extends the code name of
+// the parent template, unless it already starts with it.
+func (kt *KhataTemplate) SetCodeName(name string) *KhataTemplate {
+	kt.codeName = name
+	kt.overridden |= templateFieldCodeName
+	return kt
+}
+
+// Returns the code associated with the template, with its name and its number
+func (kt *KhataTemplate) ErrorCode() KhataCode {
+	return NewCode(kt.CodeName(), kt.Code())
+}
+
+// Sets the code name and the code number associated with the template
+func (kt *KhataTemplate) SetErrorCode(code KhataCode) *KhataTemplate {
+	return kt.SetCodeName(code.name).SetCode(code.number)
+}
+
 // Returns the error type associated with the template
 func (kt *KhataTemplate) Type() string {
-	return kt.errorType
+	return kt.lookup(templateFieldType).errorType
 }
 
 // Sets the error type associated with the template
 func (kt *KhataTemplate) SetType(errorType string) *KhataTemplate {
 	kt.errorType = errorType
+	kt.overridden |= templateFieldType
 	return kt
 }
 
 // Returns the exit code associated with the template
 func (kt *KhataTemplate) ExitCode() int {
-	return kt.exitCode
+	return kt.lookup(templateFieldExitCode).exitCode
 }
 
-// Sets the exit code associated with the template
+// Sets the exit code associated with the template, which makes its errors fatal
+// again if it or its parent was non-fatal. Templates are declared once, so it
+// panics if the exit code is not between 0 and MAX_EXIT_CODE, including
+// NON_FATAL_EXIT_CODE: use SetNonFatal instead.
 func (kt *KhataTemplate) SetExitCode(code int) *KhataTemplate {
+	mustValidExitCode(code)
 	kt.exitCode = code
+	kt.nonFatal = false
+	kt.overridden |= templateFieldExitCode | templateFieldNonFatal
 	return kt
 }
 
+// Returns the severity associated with the template. Unset severities are
+// resolved from the non-fatal flag the same way errors do.
+func (kt *KhataTemplate) Severity() Severity {
+	return resolveSeverity(kt.lookup(templateFieldSeverity).severity, kt.IsNonFatal())
+}
+
+// Sets the severity associated with the template
+func (kt *KhataTemplate) SetSeverity(severity Severity) *KhataTemplate {
+	kt.severity = severity
+	kt.overridden |= templateFieldSeverity
+	return kt
+}
+
+// Returns true if the errors created from the template capture their environment
+func (kt *KhataTemplate) CapturesEnvironment() bool {
+	return kt.lookup(templateFieldEnvironment).captureEnvironment
+}
+
+// Sets whether the errors created from the template capture the goroutine and process context.
+// The capture is disabled by default to keep the creation of errors cheap.
+func (kt *KhataTemplate) SetCaptureEnvironment(capture bool) *KhataTemplate {
+	kt.captureEnvironment = capture
+	kt.overridden |= templateFieldEnvironment
+	return kt
+}
+
+// Returns the clock used by the errors created from the template, or nil if they use the package clock.
+// The clock is inherited from the parent templates.
+func (kt *KhataTemplate) Clock() Clock {
+	for t := kt; t != nil; t = t.parent {
+		if t.clock != nil {
+			return t.clock
+		}
+	}
+	return nil
+}
+
+// Sets the clock used by the errors created from the template and its children
+func (kt *KhataTemplate) SetClock(c Clock) *KhataTemplate {
+	kt.clock = c
+	return kt
+}
+
+// Returns true if the given field is set on this template rather than inherited.
+// Valid fields are "message", "code", "codeName", "type", "exitCode", "nonFatal", "severity" and "environment".
+func (kt *KhataTemplate) IsOverridden(field string) bool {
+	flag, ok := templateFieldNames[field]
+	return ok && kt.overridden&flag != 0
+}
+
+// Returns true if the given property is set on this template rather than inherited
+func (kt *KhataTemplate) IsPropertyOverridden(key string) bool {
+	_, ok := kt.properties[key]
+	return ok || kt.removed[key]
+}
+
 // Set a property on the template
 func (kt *KhataTemplate) SetProperty(key string, value interface{}) *KhataTemplate {
 	kt.properties[key] = value
+	delete(kt.removed, key)
 	return kt
 }
 
-// Returns the keys of all the properties set on the error
+// Returns the keys of all the properties set on the template, including the inherited ones
 func (kt *KhataTemplate) PropertiesKeys() []string {
-	keys := make([]string, len(kt.properties))
-	for key := range kt.properties {
+	properties, _ := kt.resolveProperties()
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
 		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
 // Returns the value of a property associated with the template
 func (kt *KhataTemplate) GetProperty(key string) interface{} {
-	return kt.properties[key]
+	for t := kt; t != nil; t = t.parent {
+		if value, ok := t.properties[key]; ok {
+			return value
+		}
+		if t.removed[key] {
+			return nil
+		}
+	}
+	return nil
 }
 
-// Remove a property from the template
+// Remove a property from the template. Inherited properties are hidden on
+// this template and its children without modifying the parent.
 func (kt *KhataTemplate) RemoveProperty(key string) *KhataTemplate {
 	delete(kt.properties, key)
+	delete(kt.meta, key)
+	if kt.parent != nil && kt.parent.HasProperty(key) {
+		kt.removed[key] = true
+	}
 	return kt
 }
 
+func (kt *KhataTemplate) setTypedProperty(key string, value interface{}, meta propertyMeta) {
+	kt.SetProperty(key, value)
+	kt.meta[key] = meta
+}
+
+func (kt *KhataTemplate) removeTypedProperty(key string) {
+	kt.RemoveProperty(key)
+}
+
 // Check if the template has the given property
 func (kt *KhataTemplate) HasProperty(key string) bool {
-	return kt.properties[key] != nil
+	return kt.GetProperty(key) != nil
+}
+
+// Returns the template this one was extended from, or nil for root templates
+func (kt *KhataTemplate) Parent() *KhataTemplate {
+	return kt.parent
+}
+
+// Returns the parent of the template, its parent and so on up to the root template
+func (kt *KhataTemplate) Ancestors() []*KhataTemplate {
+	var ancestors []*KhataTemplate
+	for t := kt.parent; t != nil; t = t.parent {
+		ancestors = append(ancestors, t)
+	}
+	return ancestors
+}
+
+// Returns the templates directly extended from this one, in creation order.
+// Children are referenced by their parent, so templates extended per request
+// stay in memory as long as their parent does.
+func (kt *KhataTemplate) Children() []*KhataTemplate {
+	kt.childrenMutex.Lock()
+	defer kt.childrenMutex.Unlock()
+
+	children := make([]*KhataTemplate, len(kt.children))
+	copy(children, kt.children)
+	return children
 }
 
 // Returns true if the template's parent is the same as the given template
@@ -211,16 +460,64 @@ func (t *KhataTemplate) IsRelatedTo(kt *KhataTemplate) bool {
 	}
 }
 
+// Returns the closest template in the chain defining the given field
+func (kt *KhataTemplate) lookup(field int) *KhataTemplate {
+	t := kt
+	for t.overridden&field == 0 && t.parent != nil {
+		t = t.parent
+	}
+	return t
+}
+
+// Returns a copy of the effective properties of the template and their metadata
+func (kt *KhataTemplate) resolveProperties() (map[string]interface{}, map[string]propertyMeta) {
+	var properties map[string]interface{}
+	var meta map[string]propertyMeta
+
+	if kt.parent == nil {
+		properties = map[string]interface{}{}
+		meta = map[string]propertyMeta{}
+	} else {
+		properties, meta = kt.parent.resolveProperties()
+	}
+
+	for key := range kt.removed {
+		delete(properties, key)
+		delete(meta, key)
+	}
+
+	for key, value := range kt.properties {
+		properties[key] = value
+	}
+
+	for key, m := range kt.meta {
+		meta[key] = m
+	}
+
+	return properties, meta
+}
+
 type Khata struct {
 	errorCode        int
+	codeName         string
 	errorType        string
 	exitCode         int
+	nonFatal         bool
+	severity         Severity
 	createdAt        time.Time
 	Err              error
 	properties       map[string]interface{}
+	meta             map[string]propertyMeta
 	traceStack       []KhataTrace
+	traceFrozen      bool
+	creationPCs      []uintptr
+	creationTrace    []KhataTrace
 	explanationStack []KhataExplanation
 	template         *KhataTemplate
+	handledAt        time.Time
+	clock            Clock
+	environment      *KhataEnvironment
+	goroutines       []KhataGoroutineGroup
 }
 
 // Expose the error so it behaves like a normal error
@@ -228,6 +525,37 @@ func (k *Khata) Error() string {
 	return k.Err.Error()
 }
 
+// Returns the wrapped error so the standard errors.Is and errors.As functions
+// can look through the khata error
+func (k *Khata) Unwrap() error {
+	return k.Err
+}
+
+// Returns the closest khata error wrapped by this one, or nil if there is none.
+// The wrapped error can itself be wrapped by other errors, like fmt.Errorf with %w.
+func (k *Khata) Cause() *Khata {
+	var cause *Khata
+	if k.Err != nil && errors.As(k.Err, &cause) {
+		return cause
+	}
+	return nil
+}
+
+// Returns the wrap chain, starting with this error and ending with the root cause
+func (k *Khata) Chain() []*Khata {
+	chain := []*Khata{k}
+	for cause := k.Cause(); cause != nil; cause = cause.Cause() {
+		chain = append(chain, cause)
+	}
+	return chain
+}
+
+// Returns the innermost khata error of the wrap chain. Returns the error itself if it does not wrap another one.
+func (k *Khata) RootCause() *Khata {
+	chain := k.Chain()
+	return chain[len(chain)-1]
+}
+
 // Allows you to change the initial error (This should be used with caution)
 func (k *Khata) SetError(err error) *Khata {
 	k.Err = err
@@ -252,6 +580,11 @@ func (k *Khata) IsAny(errs ...error) bool {
 	return false
 }
 
+// Returns the template the error was created from, or nil
+func (k *Khata) Template() *KhataTemplate {
+	return k.template
+}
+
 // Returns true if the error's template is the same as the given template
 func (k *Khata) IsInstanceOf(kt *KhataTemplate) bool {
 	return k.template == kt
@@ -272,13 +605,41 @@ func (k *Khata) IsRelatedTo(kt *KhataTemplate) bool {
 }
 
 // Returns the trace stack as an array of objects. Will be computed at the time of calling.
+// Errors decoded with FromJSON keep the trace they were serialized with.
 func (k *Khata) Trace() []KhataTrace {
+	if k.traceFrozen {
+		return k.traceStack
+	}
+
 	trace := collectTrace()
 	k.traceStack = trace
 
 	return k.traceStack
 }
 
+// Returns the trace stack captured when the error was created. Resolved on the first call.
+// Errors decoded with FromJSON return the trace they were serialized with.
+func (k *Khata) CreationTrace() []KhataTrace {
+	if k.traceFrozen {
+		return k.traceStack
+	}
+
+	if k.creationTrace == nil && k.creationPCs != nil {
+		k.creationTrace = resolveTrace(k.creationPCs)
+	}
+
+	return k.creationTrace
+}
+
+// Captures the creation trace, as program counters unless a stack capture is set
+func (k *Khata) captureCreation() {
+	if capture := currentStackCapture(); capture != nil {
+		k.creationTrace = capture.Trace()
+		return
+	}
+	k.creationPCs = callers()
+}
+
 // Returns the code of the error. If not set, defaults to -1
 func (k *Khata) Code() int {
 	return k.errorCode
@@ -290,11 +651,46 @@ func (k *Khata) SetCode(code int) *Khata {
 	return k
 }
 
-// Check if the error is the same as the given code
+// Returns the code name of the error, empty if not set
+func (k *Khata) CodeName() string {
+	return k.codeName
+}
+
+// Set the code name of the error, like AUTH.TOKEN.EXPIRED
+func (k *Khata) SetCodeName(name string) *Khata {
+	k.codeName = name
+	return k
+}
+
+// Returns the code of the error, with its name and its number
+func (k *Khata) ErrorCode() KhataCode {
+	return NewCode(k.codeName, k.errorCode)
+}
+
+// Set the code name and the code number of the error
+func (k *Khata) SetErrorCode(code KhataCode) *Khata {
+	k.codeName = code.name
+	k.errorCode = code.number
+	return k
+}
+
+// Check if the error has the given code number
 func (k *Khata) IsCode(code int) bool {
 	return k.errorCode == code
 }
 
+// Check if the code of the error matches the pattern, like AUTH.* or 404.
+// See KhataCode.Matches.
+func (k *Khata) MatchCode(pattern string) bool {
+	return k.ErrorCode().Matches(pattern)
+}
+
+// Check if the error has the given code. Only the name and the number set on
+// the code are compared, so NamedCode("AUTH.TOKEN.EXPIRED") matches whatever the number.
+func (k *Khata) IsKhataCode(code KhataCode) bool {
+	return k.ErrorCode().Has(code)
+}
+
 // Check if the error is any of the given codes
 func (k *Khata) IsAnyCode(codes ...int) bool {
 	for _, code := range codes {
@@ -331,12 +727,13 @@ func (k *Khata) IsAnyType(errorTypes ...string) bool {
 	return false
 }
 
-// Returns the keys of all the properties set on the error
+// Returns the keys of all the properties set on the error, sorted
 func (k *Khata) PropertiesKeys() []string {
-	keys := make([]string, len(k.properties))
+	keys := make([]string, 0, len(k.properties))
 	for key := range k.properties {
 		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
@@ -359,17 +756,35 @@ func (k *Khata) SetProperty(key string, value interface{}) *Khata {
 // Remove a property from the khata error
 func (k *Khata) RemoveProperty(key string) *Khata {
 	delete(k.properties, key)
+	delete(k.meta, key)
 	return k
 }
 
+func (k *Khata) setTypedProperty(key string, value interface{}, meta propertyMeta) {
+	k.properties[key] = value
+	k.meta[key] = meta
+}
+
+func (k *Khata) removeTypedProperty(key string) {
+	k.RemoveProperty(key)
+}
+
 // Returns the exit code from the khata error. If not set, defaults to 1
 func (k *Khata) ExitCode() int {
 	return k.exitCode
 }
 
-// Set the exit code of the program. If not set, defaults to 1
+// Set the exit code of the program, which makes a non-fatal error fatal again.
+// If not set, defaults to 1. Exit codes are often set at runtime, like the -1 of
+// exec.ExitError.ExitCode for a child killed by a signal, so the ones that are
+// not between 0 and MAX_EXIT_CODE are replaced by DEFAULT_EXIT_CODE.
 func (k *Khata) SetExitCode(code int) *Khata {
+	if !ValidExitCode(code) {
+		code = DEFAULT_EXIT_CODE
+	}
+
 	k.exitCode = code
+	k.nonFatal = false
 	return k
 }
 
@@ -388,6 +803,23 @@ func (k *Khata) IsAnyExitCode(codes ...int) bool {
 	return false
 }
 
+// Returns the severity of the error. If not set explicitly, non-fatal errors
+// are SeverityError and all others are SeverityFatal.
+func (k *Khata) Severity() Severity {
+	return resolveSeverity(k.severity, k.nonFatal)
+}
+
+// Set the severity of the error
+func (k *Khata) SetSeverity(severity Severity) *Khata {
+	k.severity = severity
+	return k
+}
+
+// Check if the error is at least as severe as the given severity
+func (k *Khata) IsSeverity(severity Severity) bool {
+	return k.Severity().AtLeast(severity)
+}
+
 // Returns the explanations for the error
 func (k *Khata) Explanations() []KhataExplanation {
 	return k.explanationStack
@@ -395,205 +827,256 @@ func (k *Khata) Explanations() []KhataExplanation {
 
 // Add an explanation to the error
 func (k *Khata) Explain(explanation string) *Khata {
-	lastTrace := collectCallerTrace()
-
-	k.explanationStack = append(k.explanationStack, KhataExplanation{
-		Message:      explanation,
-		File:         lastTrace.file,
-		Line:         lastTrace.line,
-		FunctionName: lastTrace.functionName,
-	})
-
+	k.addExplanation(collectCallerTrace(), explanation, nil)
 	return k
 }
 
 // Explainf is a wrapper around Explain that accepts a format string
 func (k *Khata) Explainf(format string, args ...interface{}) *Khata {
-	lastTrace := collectCallerTrace()
+	k.addExplanation(collectCallerTrace(), fmt.Sprintf(format, args...), nil)
+	return k
+}
 
-	k.explanationStack = append(k.explanationStack, KhataExplanation{
-		Message:      fmt.Sprintf(format, args...),
-		File:         lastTrace.file,
-		Line:         lastTrace.line,
-		FunctionName: lastTrace.functionName,
-	})
+// ExplainWith adds an explanation carrying structured fields given as alternating keys and values.
+//
+//	k.ExplainWith("retrying", "attempt", 3, "host", host)
+//
+// Keys that are not strings are formatted with %v and a trailing value without a key is stored under "!BADKEY".
+func (k *Khata) ExplainWith(explanation string, keyValues ...interface{}) *Khata {
+	fields := make(map[string]interface{}, len(keyValues)/2)
+
+	for i := 0; i < len(keyValues); i += 2 {
+		if i == len(keyValues)-1 {
+			fields["!BADKEY"] = keyValues[i]
+			break
+		}
 
+		key, ok := keyValues[i].(string)
+		if !ok {
+			key = fmt.Sprintf("%v", keyValues[i])
+		}
+
+		fields[key] = keyValues[i+1]
+	}
+
+	k.addExplanation(collectCallerTrace(), explanation, fields)
 	return k
 }
 
+func (k *Khata) addExplanation(trace KhataTrace, message string, fields map[string]interface{}) {
+	k.explanationStack = append(k.explanationStack, KhataExplanation{
+		Message:      message,
+		File:         trace.file,
+		Line:         trace.line,
+		FunctionName: trace.functionName,
+		Fields:       fields,
+		Elapsed:      now(k.clock).Sub(k.createdAt),
+		Goroutine:    goroutine.ID(),
+	})
+}
+
 // Check if the error is fatal. Fatal errors are those that should stop the program.
 func (k *Khata) IsFatal() bool {
-	return k.exitCode != -1
+	return k.Severity() == SeverityFatal
 }
 
-// Print the error in a console friendly way
+// Print the error in a console friendly way on the standard error
 func (k *Khata) Debug() *Khata {
-	handledAt := time.Now().UTC()
+	return k.DebugTo(os.Stderr)
+}
+
+// Print the error in a console friendly way on the given writer
+func (k *Khata) DebugTo(w io.Writer) *Khata {
+	currentConfig().DebugTo(w, k)
+	return k
+}
+
+// Print the error in a console friendly way on the given writer, using this configuration
+func (c *Config) DebugTo(w io.Writer, k *Khata) {
+	pal := c.palette()
+	handledAt := k.handledTime()
 	diff := handledAt.Sub(k.createdAt)
 
 	// Print error
 	p := fmt.Sprintf(
 		"\n%s%s%s",
-		colors.BoldRed,
+		pal.severity(k.Severity()),
 		k.Err,
-		colors.Reset,
+		pal.Reset,
 	)
-	println(p)
+	fmt.Fprintln(w, p)
 
 	// Print explanations
-	explanations := k.Explanations()
+	fmt.Fprintf(w, "\n=== %sExplanations%s\n", pal.BoldYellow, pal.Reset)
 
-	println(fmt.Sprintf("\n=== %sExplanations%s", colors.BoldYellow, colors.Reset))
-
-	for _, explanation := range explanations {
-		file := tryTrimmingPath(explanation.File)
-		funcName := tryTrimmingFunc(explanation.FunctionName)
-		p := fmt.Sprintf(
-			"  %s%s%s:%s%d%s (%s%s%s)\n  └── %s%s%s",
-			colors.UnderlineGray,
-			file,
-			colors.Reset,
-			colors.Green,
-			explanation.Line,
-			colors.Reset,
-			colors.Cyan,
-			funcName,
-			colors.Reset,
-			colors.BoldWhite,
-			explanation.Message,
-			colors.Reset,
-		)
-		fmt.Println(p)
-	}
+	c.printExplanations(w, pal, k.Explanations())
 
 	// Print trace
-	trace := k.Trace()
+	trace := c.frames(k.Trace())
 
-	println(fmt.Sprintf("\n=== %sTrace%s", colors.BoldYellow, colors.Reset))
+	fmt.Fprintf(w, "\n=== %sTrace%s\n", pal.BoldYellow, pal.Reset)
 
 	for _, trace := range trace {
-		file := tryTrimmingPath(trace.file)
-		funcName := tryTrimmingFunc(trace.functionName)
+		file := c.trimPath(trace.file)
+		funcName := c.trimFunc(trace.functionName)
+
+		// Dependency frames are dimmed when the main module is known
+		funcColor := pal.Cyan
+		if mainModule() != "" && !trace.IsOwnCode() {
+			funcColor = pal.Gray
+		}
+
 		p := fmt.Sprintf(
 			"  %s%s%s:%s%d%s (%s%s%s)",
-			colors.UnderlineGray,
+			pal.UnderlineGray,
 			file,
-			colors.Reset,
-			colors.Green,
+			pal.Reset,
+			pal.Green,
 			trace.line,
-			colors.Reset,
-			colors.Cyan,
+			pal.Reset,
+			funcColor,
 			funcName,
-			colors.Reset,
+			pal.Reset,
 		)
-		fmt.Println(p)
+
+		if trace.folded != 0 {
+			p += fmt.Sprintf(" %s+%d folded frames%s", pal.Gray, trace.folded, pal.Reset)
+		}
+
+		fmt.Fprintln(w, p)
 	}
 
-	println(fmt.Sprintf("\n=== %sDetails%s", colors.BoldYellow, colors.Reset))
+	fmt.Fprintf(w, "\n=== %sDetails%s\n", pal.BoldYellow, pal.Reset)
 
-	println(fmt.Sprintf("  %sError Type%s: %s%s%s", colors.BoldWhite, colors.Reset, colors.Cyan, k.errorType, colors.Reset))
-	println(fmt.Sprintf("  %sError Code%s: %s%d%s", colors.BoldWhite, colors.Reset, colors.Cyan, k.errorCode, colors.Reset))
-	println(fmt.Sprintf("  %sExit Code%s: %s%d%s", colors.BoldWhite, colors.Reset, colors.Cyan, k.exitCode, colors.Reset))
-	println(fmt.Sprintf("  %sError At%s: %s%s%s", colors.BoldWhite, colors.Reset, colors.Cyan, k.createdAt.Format("2006/01/02 15:04:05 0.000ms"), colors.Reset))
-	println(fmt.Sprintf("  %sHandled At%s: %s%s%s", colors.BoldWhite, colors.Reset, colors.Cyan, handledAt.Format("2006/01/02 15:04:05 0.000ms"), colors.Reset))
-	println(fmt.Sprintf("  %sEnlapse Time%s: %s%.3fs%s", colors.BoldWhite, colors.Reset, colors.Cyan, (float64(diff.Milliseconds()) / 1000), colors.Reset))
+	fmt.Fprintf(w, "  %sError Type%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, k.errorType, pal.Reset)
+	fmt.Fprintf(w, "  %sError Code%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, k.ErrorCode(), pal.Reset)
+	fmt.Fprintf(w, "  %sExit Code%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, k.describeExitCode(), pal.Reset)
+	fmt.Fprintf(w, "  %sSeverity%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, k.Severity(), pal.Reset)
+	fmt.Fprintf(w, "  %sError At%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, k.createdAt.Format(debugTimeLayout), pal.Reset)
+	fmt.Fprintf(w, "  %sHandled At%s: %s%s%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, handledAt.Format(debugTimeLayout), pal.Reset)
+	fmt.Fprintf(w, "  %sEnlapse Time%s: %s%.3fs%s\n", pal.BoldWhite, pal.Reset, pal.Cyan, (float64(diff.Milliseconds()) / 1000), pal.Reset)
 
-	if len(k.properties) == 0 {
-		fmt.Println()
-		return k
-	}
+	if k.environment != nil {
+		fmt.Fprintf(w, "\n=== %sEnvironment%s\n", pal.BoldYellow, pal.Reset)
 
-	println(fmt.Sprintf("\n=== %sProperties%s", colors.BoldYellow, colors.Reset))
+		printProperties(w, pal, k.environment.entries())
+	}
 
-	longestKey := 0
+	if len(k.properties) != 0 {
+		fmt.Fprintf(w, "\n=== %sProperties%s\n", pal.BoldYellow, pal.Reset)
 
-	for key := range k.properties {
-		if len(key) > longestKey {
-			longestKey = len(key)
-		}
+		printProperties(w, pal, renderProperties(k.properties, k.meta, false))
 	}
 
-	for key, value := range k.properties {
-		spaces := ""
+	if len(k.goroutines) != 0 {
+		fmt.Fprintf(w, "\n=== %sGoroutines%s\n", pal.BoldYellow, pal.Reset)
 
-		for i := 0; i < longestKey-len(key); i++ {
-			spaces += " "
-		}
+		c.printGoroutines(w, pal, k.goroutines)
+	}
 
+	// Print the inner layers of the wrap chain
+	for _, cause := range k.Chain()[1:] {
 		p := fmt.Sprintf(
-			"  %s%s%s%s -> %s%v%s",
-			colors.BoldWhite,
-			key,
-			colors.Reset,
-			spaces,
-			colors.Cyan,
-			value,
-			colors.Reset,
+			"\n=== %sCaused by%s %s%s%s (%s%s%s, code %s%s%s)",
+			pal.BoldYellow,
+			pal.Reset,
+			pal.severity(cause.Severity()),
+			cause.Err,
+			pal.Reset,
+			pal.Cyan,
+			cause.errorType,
+			pal.Reset,
+			pal.Cyan,
+			cause.ErrorCode(),
+			pal.Reset,
 		)
-		fmt.Println(p)
+		fmt.Fprintln(w, p)
+
+		c.printExplanations(w, pal, cause.Explanations())
+
+		if len(cause.properties) != 0 {
+			fmt.Fprintf(w, "  %sProperties%s\n", pal.BoldWhite, pal.Reset)
+			printProperties(w, pal, renderProperties(cause.properties, cause.meta, false))
+		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(w)
+}
+
+// Returns the time at which the error was created
+func (k *Khata) CreatedAt() time.Time {
+	return k.createdAt
+}
 
+// Marks the error as handled at the current time. Only the first call has an effect,
+// so the handling time stays stable across the renders that follow.
+func (k *Khata) MarkHandled() *Khata {
+	if k.handledAt.IsZero() {
+		k.handledAt = now(k.clock)
+		notifyHandled(k)
+	}
 	return k
 }
 
-// Returns a JSON string representation of the error.
-// This is useful to log or store the error.
-// The handledAt and trace will be generated at the time of calling this method.
-func (k *Khata) ToJSON() string {
-	trace := k.Trace()
-	explanations := k.Explanations()
+// Returns the time at which the error was marked as handled, or the zero time if it was not
+func (k *Khata) HandledAt() time.Time {
+	return k.handledAt
+}
 
-	traceMap := make([]map[string]interface{}, len(trace))
-	explanationsMap := make([]map[string]interface{}, len(explanations))
+// Returns the handling time, or the current time if the error was not marked as handled
+func (k *Khata) handledTime() time.Time {
+	if k.handledAt.IsZero() {
+		return now(k.clock)
+	}
+	return k.handledAt
+}
+
+// Returns a short identifier shared by errors of the same kind raised from the same place.
+// It is computed from the type, code, message and the functions that explained the error,
+// so it is stable across occurrences and across processes.
+func (k *Khata) Fingerprint() string {
+	hash := sha256.New()
 
-	for i, t := range trace {
-		traceMap[i] = map[string]interface{}{
-			"file":         t.file,
-			"line":         t.line,
-			"functionName": t.functionName,
+	for _, layer := range k.Chain() {
+		fmt.Fprintf(hash, "%s\x00%d\x00", layer.errorType, layer.errorCode)
+
+		if layer.codeName != "" {
+			fmt.Fprintf(hash, "%s\x00", layer.codeName)
 		}
-	}
 
-	for i, e := range explanations {
-		explanationsMap[i] = map[string]interface{}{
-			"file":         e.File,
-			"line":         e.Line,
-			"functionName": e.FunctionName,
-			"message":      e.Message,
+		for _, explanation := range layer.explanationStack {
+			fmt.Fprintf(hash, "%s\x00", explanation.FunctionName)
 		}
 	}
 
-	jsonStr, err := json.Marshal(map[string]interface{}{
-		"trace":        traceMap,
-		"explanations": explanationsMap,
-		"error":        k.Err.Error(),
-		"errorType":    k.errorType,
-		"errorCode":    k.errorCode,
-		"exitCode":     k.exitCode,
-		"createdAt":    k.createdAt.Format("2006-01-02T15:04:05.000Z-0700"),
-		"properties":   k.properties,
-		"handledAt":    time.Now().UTC().Format("2006-01-02T15:04:05.000Z-0700"),
-	})
+	fmt.Fprintf(hash, "%s", k.RootCause().Err)
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
 
-	if err != nil {
-		return ""
+// Create the default khata error type, or an error of the configured default template
+func Wrap(err error) *Khata {
+	if template := currentConfig().DefaultTemplate; template != nil {
+		return template.Wrap(err)
 	}
 
-	return string(jsonStr)
+	k := wrap(err)
+	k.captureCreation()
+	notifyCreated(k)
+	return k
 }
 
-// Create the default khata error type
-func Wrap(err error) *Khata {
+// Create the default khata error type without notifying the observers
+func wrap(err error) *Khata {
 	return &Khata{
 		Err:              err,
-		createdAt:        time.Now().UTC(),
+		createdAt:        now(nil),
 		errorCode:        DEFAULT_ERROR_CODE,
 		errorType:        DEFAULT_ERROR_TYPE,
 		exitCode:         DEFAULT_EXIT_CODE,
 		explanationStack: []KhataExplanation{},
 		properties:       map[string]interface{}{},
+		meta:             map[string]propertyMeta{},
 		traceStack:       []KhataTrace{},
 		template:         nil,
 	}
@@ -605,58 +1088,130 @@ func New(message string) *Khata {
 
 // KhataTemplate
 
-// Create a new KhataTemplate with the given error type as an optional argument. Expects a string.
+// Create a new root KhataTemplate. Root templates define every field with its default value.
 func NewTemplate() *KhataTemplate {
 	return &KhataTemplate{
 		errorCode:  DEFAULT_ERROR_CODE,
 		errorType:  DEFAULT_ERROR_TYPE,
 		exitCode:   DEFAULT_EXIT_CODE,
+		overridden: templateFieldAll,
 		properties: map[string]interface{}{},
+		meta:       map[string]propertyMeta{},
+		removed:    map[string]bool{},
 		message:    DEFAULT_MESSAGE,
 	}
 }
 
 // The default error handler for Khata errors.
-// It will print the debugging information. Will exit the program if the error is fatal.
+// It will print the debugging information. Will exit the program if the error is fatal,
+// after attaching a goroutine dump if Config.DumpGoroutinesOnFatal is set.
+// The program exits with ExitStatus(), so errors originating from a signal exit with 128 plus the signal number.
 func HandleKhata(khataError Khata) {
+	khataError.MarkHandled()
+
+	if khataError.IsFatal() && currentConfig().DumpGoroutinesOnFatal {
+		khataError.AttachGoroutineDump()
+	}
+
 	khataError.Debug()
 
 	if khataError.IsFatal() {
-		os.Exit(khataError.exitCode)
+		os.Exit(khataError.ExitStatus())
 	}
 }
 
 // Private Functions
 
-func tryTrimmingFunc(funcName string) string {
-	prefix := os.Getenv("KHATA_FUNC_TRUNC_PREFIX")
+func (c *Config) printExplanations(w io.Writer, pal palette, explanations []KhataExplanation) {
+	var previous time.Duration
 
-	if prefix == "" {
-		return funcName
-	}
+	for _, explanation := range explanations {
+		file := c.trimPath(explanation.File)
+		funcName := c.trimFunc(explanation.FunctionName)
+		p := fmt.Sprintf(
+			"  %s+%s%s (Δ %s) %s%s%s:%s%d%s (%s%s%s) %sg%d%s\n  └── %s%s%s",
+			pal.Yellow,
+			formatDuration(explanation.Elapsed),
+			pal.Reset,
+			formatDuration(explanation.Elapsed-previous),
+			pal.UnderlineGray,
+			file,
+			pal.Reset,
+			pal.Green,
+			explanation.Line,
+			pal.Reset,
+			pal.Cyan,
+			funcName,
+			pal.Reset,
+			pal.Gray,
+			explanation.Goroutine,
+			pal.Reset,
+			pal.BoldWhite,
+			explanation.Message,
+			pal.Reset,
+		)
 
-	if strings.HasPrefix(funcName, prefix) {
-		return funcName[len(prefix):]
-	}
+		keys := make([]string, 0, len(explanation.Fields))
+		for key := range explanation.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			p += fmt.Sprintf(" %s%s%s=%v", pal.Cyan, key, pal.Reset, explanation.Fields[key])
+		}
 
-	return funcName
+		fmt.Fprintln(w, p)
+		previous = explanation.Elapsed
+	}
 }
 
-func tryTrimmingPath(filePath string) string {
-	prefix := os.Getenv("KHATA_PATH_TRUNC_PREFIX")
+func printProperties(w io.Writer, pal palette, properties map[string]interface{}) {
+	longestKey := 0
 
-	if prefix == "" {
-		return filePath
+	for key := range properties {
+		if len(key) > longestKey {
+			longestKey = len(key)
+		}
 	}
 
-	if strings.HasPrefix(filePath, prefix) {
-		return filePath[len(prefix):]
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := properties[key]
+		spaces := ""
+
+		for i := 0; i < longestKey-len(key); i++ {
+			spaces += " "
+		}
+
+		p := fmt.Sprintf(
+			"  %s%s%s%s -> %s%v%s",
+			pal.BoldWhite,
+			key,
+			pal.Reset,
+			spaces,
+			pal.Cyan,
+			value,
+			pal.Reset,
+		)
+		fmt.Fprintln(w, p)
+	}
+}
 
-	return filePath
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d.Microseconds())/1000)
 }
 
 func collectCallerTrace() KhataTrace {
+	if capture := currentStackCapture(); capture != nil {
+		return capture.Caller()
+	}
+
 	var pc [128]uintptr
 	depth := runtime.Callers(3, pc[:])
 	frames := runtime.CallersFrames(pc[:depth])
@@ -670,29 +1225,46 @@ func collectCallerTrace() KhataTrace {
 }
 
 func collectTrace() []KhataTrace {
+	if capture := currentStackCapture(); capture != nil {
+		return capture.Trace()
+	}
+
+	return resolveTrace(callers())
+}
+
+// Returns the program counters of the whole calling stack
+func callers() []uintptr {
+	// Grow the buffer until it holds the whole stack
+	pc := make([]uintptr, 64)
+	depth := runtime.Callers(1, pc)
+	for depth == len(pc) {
+		pc = make([]uintptr, len(pc)*2)
+		depth = runtime.Callers(1, pc)
+	}
+	return pc[:depth]
+}
+
+// Resolves program counters into trace entries, leaving out the frames of this package
+func resolveTrace(pc []uintptr) []KhataTrace {
 	const packagePrefix = "github.com/cmseguin/khata."
 
-	var pc [128]uintptr
-	depth := runtime.Callers(1, pc[:])
-	frames := runtime.CallersFrames(pc[:depth])
+	frames := runtime.CallersFrames(pc)
 
-	var trace []KhataTrace
+	trace := []KhataTrace{}
 	for {
 		frame, more := frames.Next()
 
-		if !more {
-			break
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, packagePrefix) {
+			trace = append(trace, KhataTrace{
+				file:         frame.File,
+				line:         frame.Line,
+				functionName: frame.Function,
+			})
 		}
 
-		if len(frame.Function) > len(packagePrefix) && packagePrefix == frame.Function[:len(packagePrefix)] {
-			continue
+		if !more {
+			break
 		}
-
-		trace = append(trace, KhataTrace{
-			file:         frame.File,
-			line:         frame.Line,
-			functionName: frame.Function,
-		})
 	}
 	return trace
 }
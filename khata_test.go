@@ -1,8 +1,8 @@
 package khata_test
 
 import (
+	"encoding/json"
 	"errors"
-	"os"
 	"testing"
 
 	"github.com/cmseguin/khata"
@@ -26,6 +26,19 @@ func TestWrapKhata(t *testing.T) {
 	}
 }
 
+func createKhata() *khata.Khata {
+	return khata.New("This is an error message")
+}
+
+func TestKhataCreationTrace(t *testing.T) {
+	trace := createKhata().CreationTrace()
+
+	if len(trace) == 0 || trace[0].FunctionName() != "github.com/cmseguin/khata_test.createKhata" {
+		t.Error("CreationTrace() did not start with the function creating the error")
+		return
+	}
+}
+
 func subFnExplain(err *khata.Khata) *khata.Khata {
 	err.Explain("This is an explanation for subFnExplain")
 	return err
@@ -265,8 +278,11 @@ func TestKhataErrorChaining(t *testing.T) {
 }
 
 func TestDebugOutput(t *testing.T) {
-	os.Setenv("KHATA_FUNC_TRUNC_PREFIX", "github.com/cmseguin/")
-	os.Setenv("KHATA_PATH_TRUNC_PREFIX", "/Users/cmseguin/dev/git/khata/")
+	defer khata.SetConfig(khata.CurrentConfig())
+	khata.SetConfig(khata.Config{
+		FuncTrimPrefixes: []string{"github.com/cmseguin/"},
+		PathTrimPrefixes: []string{"/Users/cmseguin/dev/git/khata/"},
+	})
 
 	k := khata.
 		New("Not Found").
@@ -280,3 +296,94 @@ func TestDebugOutput(t *testing.T) {
 
 	k.Debug()
 }
+
+func TestKhataExplainWith(t *testing.T) {
+	k := khata.New("This is an error message")
+
+	k.ExplainWith("retrying", "attempt", 3, "host", "localhost", "dangling")
+
+	if len(k.Explanations()) != 1 {
+		t.Error("ExplainWith() did not set the explanation")
+		return
+	}
+
+	explanation := k.Explanations()[0]
+
+	if explanation.FunctionName != "github.com/cmseguin/khata_test.TestKhataExplainWith" {
+		t.Error("ExplainWith() did not set the function name")
+		return
+	}
+
+	if explanation.Fields["attempt"] != 3 || explanation.Fields["host"] != "localhost" || explanation.Fields["!BADKEY"] != "dangling" {
+		t.Error("ExplainWith() did not set the fields")
+		return
+	}
+
+	if explanation.Goroutine == 0 || explanation.Elapsed < 0 {
+		t.Error("ExplainWith() did not set the goroutine and elapsed time")
+		return
+	}
+
+	var output struct {
+		Explanations []struct {
+			Fields map[string]interface{} `json:"fields"`
+		} `json:"explanations"`
+	}
+
+	if err := json.Unmarshal([]byte(k.ToJSON()), &output); err != nil {
+		t.Error("ToJSON() did not return valid JSON")
+		return
+	}
+
+	if output.Explanations[0].Fields["attempt"] != float64(3) {
+		t.Error("ToJSON() did not keep the field values structured")
+		return
+	}
+}
+
+func TestKhataFromJSON(t *testing.T) {
+	inner := khata.New("connection refused").SetType("Database").Explain("This is an explanation of the database")
+	k := khata.Wrap(inner).
+		SetType("HTTP").
+		SetCode(503).
+		SetNonFatal().
+		SetProperty("test", "testValue").
+		ExplainWith("This is an explanation", "attempt", 3)
+
+	decoded, err := khata.FromJSON([]byte(k.ToJSON()))
+
+	if err != nil {
+		t.Error("FromJSON() returned an error for a valid khata error")
+		return
+	}
+
+	if decoded.Error() != k.Error() || decoded.Type() != "HTTP" || decoded.Code() != 503 || !decoded.IsNonFatal() {
+		t.Error("FromJSON() did not decode the details")
+		return
+	}
+
+	if decoded.GetProperty("test") != "testValue" || decoded.Explanations()[0].Fields["attempt"] != float64(3) {
+		t.Error("FromJSON() did not decode the properties and explanations")
+		return
+	}
+
+	if decoded.Cause() == nil || decoded.Cause().Type() != "Database" {
+		t.Error("FromJSON() did not decode the cause")
+		return
+	}
+
+	if len(decoded.Trace()) == 0 || decoded.Trace()[0].FunctionName() != "github.com/cmseguin/khata_test.TestKhataFromJSON" {
+		t.Error("FromJSON() did not keep the serialized trace")
+		return
+	}
+
+	if decoded.Fingerprint() != k.Fingerprint() {
+		t.Error("Fingerprint() changed after a round trip")
+		return
+	}
+
+	if _, err := khata.FromJSON([]byte(`{"msg":"not a khata error"}`)); err == nil {
+		t.Error("FromJSON() did not return an error for a document that is not a khata error")
+		return
+	}
+}
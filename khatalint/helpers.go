@@ -0,0 +1,222 @@
+package khatalint
+
+import (
+	"bytes"
+	"go/ast"
+	"go/constant"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Methods returning their receiver, or a new value, that can be chained
+var chainMethods = map[string]bool{
+	"New":            true,
+	"Wrap":           true,
+	"Extend":         true,
+	"Explain":        true,
+	"Explainf":       true,
+	"ExplainWith":    true,
+	"RemoveProperty": true,
+}
+
+func calledFunc(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	fn, _ := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	return fn
+}
+
+// Returns true if fn is the given package level function
+func isFunc(fn *types.Func, pkg string, name string) bool {
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == pkg && fn.Name() == name && recvName(fn) == ""
+}
+
+// Returns true if fn is the given method of a khata type
+func isKhataMethod(fn *types.Func, recv string, name string) bool {
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == khataPath && fn.Name() == name && recvName(fn) == recv
+}
+
+func recvName(fn *types.Func) string {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return ""
+	}
+
+	t := sig.Recv().Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+
+	return ""
+}
+
+// Returns true for khata functions and methods returning an error or template meant to be chained
+func isChainFunc(fn *types.Func) bool {
+	if isConstructor(fn) {
+		return true
+	}
+
+	recv := recvName(fn)
+	if fn.Pkg() == nil || fn.Pkg().Path() != khataPath || (recv != "Khata" && recv != "KhataTemplate") {
+		return false
+	}
+
+	return chainMethods[fn.Name()] || strings.HasPrefix(fn.Name(), "Set")
+}
+
+// Returns true for khata functions and methods creating a new error or template
+func isConstructor(fn *types.Func) bool {
+	return isFunc(fn, khataPath, "New") ||
+		isFunc(fn, khataPath, "Wrap") ||
+		isFunc(fn, khataPath, "NewTemplate") ||
+		isKhataMethod(fn, "KhataTemplate", "New") ||
+		isKhataMethod(fn, "KhataTemplate", "Wrap") ||
+		isKhataMethod(fn, "KhataTemplate", "Extend")
+}
+
+// Returns true for methods modifying a template
+func isTemplateMutator(fn *types.Func) bool {
+	if recvName(fn) != "KhataTemplate" || fn.Pkg() == nil || fn.Pkg().Path() != khataPath {
+		return false
+	}
+	return strings.HasPrefix(fn.Name(), "Set") || fn.Name() == "RemoveProperty"
+}
+
+func describe(fn *types.Func) string {
+	if recv := recvName(fn); recv != "" {
+		return recv + "." + fn.Name()
+	}
+	return "khata." + fn.Name()
+}
+
+// Returns true if the setter chain ending with expr starts from a template shared
+// with other functions: a package level variable, a parameter or a field.
+// Templates created or extended in the function are its own to configure.
+func isSharedTemplate(pass *analysis.Pass, expr ast.Expr, params map[*types.Var]bool) bool {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			v, ok := pass.TypesInfo.ObjectOf(e).(*types.Var)
+			return ok && (isPackageVar(v) || params[v])
+		case *ast.SelectorExpr:
+			if selection, ok := pass.TypesInfo.Selections[e]; ok {
+				return selection.Kind() == types.FieldVal
+			}
+			v, ok := pass.TypesInfo.ObjectOf(e.Sel).(*types.Var)
+			return ok && isPackageVar(v)
+		case *ast.CallExpr:
+			fn := calledFunc(pass, e)
+			selector, ok := ast.Unparen(e.Fun).(*ast.SelectorExpr)
+			if fn == nil || !ok || !isChainFunc(fn) || isConstructor(fn) {
+				return false
+			}
+			expr = selector.X
+		default:
+			return false
+		}
+	}
+}
+
+func isPackageVar(v *types.Var) bool {
+	return v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// Returns the parameters of the function and of the closures declared in it
+func funcParams(pass *analysis.Pass, fn *ast.FuncDecl) map[*types.Var]bool {
+	params := map[*types.Var]bool{}
+	ast.Inspect(fn, func(n ast.Node) bool {
+		var t *ast.FuncType
+		switch f := n.(type) {
+		case *ast.FuncDecl:
+			t = f.Type
+		case *ast.FuncLit:
+			t = f.Type
+		default:
+			return true
+		}
+
+		for _, field := range t.Params.List {
+			for _, name := range field.Names {
+				if v, ok := pass.TypesInfo.Defs[name].(*types.Var); ok {
+					params[v] = true
+				}
+			}
+		}
+		return true
+	})
+	return params
+}
+
+// Returns the variable at the start of a setter chain, like T in T.SetCode(1).SetType("x")
+func chainRootVar(pass *analysis.Pass, expr ast.Expr) *types.Var {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			v, _ := pass.TypesInfo.ObjectOf(e).(*types.Var)
+			return v
+		case *ast.CallExpr:
+			fn := calledFunc(pass, e)
+			selector, ok := ast.Unparen(e.Fun).(*ast.SelectorExpr)
+			if fn == nil || !ok || !isChainFunc(fn) || isConstructor(fn) {
+				return nil
+			}
+			expr = selector.X
+		default:
+			return nil
+		}
+	}
+}
+
+// Returns true for calls building a new error value that cannot be compared by identity
+func isFreshError(pass *analysis.Pass, expr ast.Expr) bool {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	fn := calledFunc(pass, call)
+	return isFunc(fn, "errors", "New") || isFunc(fn, "fmt", "Errorf")
+}
+
+func isErrorInterface(t types.Type) bool {
+	return t != nil && types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isKhata(t types.Type) bool {
+	return isKhataPointer(t, "Khata")
+}
+
+func isTemplate(t types.Type) bool {
+	return isKhataPointer(t, "KhataTemplate")
+}
+
+func isKhataPointer(t types.Type, name string) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+
+	named, ok := ptr.Elem().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == khataPath && named.Obj().Name() == name
+}
+
+func constantString(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	value := pass.TypesInfo.Types[expr].Value
+	if value == nil || value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(value), true
+}
+
+func render(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	return buf.String()
+}
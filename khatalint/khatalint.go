@@ -0,0 +1,512 @@
+// Package khatalint defines an analyzer reporting common mistakes made while
+// using khata errors and templates.
+//
+// It can be run with go vet:
+//
+//	go install github.com/cmseguin/khata/cmd/khatalint@latest
+//	go vet -vettool=$(which khatalint) ./...
+package khatalint
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const khataPath = "github.com/cmseguin/khata"
+
+// Values of khata.MAX_EXIT_CODE and khata.NON_FATAL_EXIT_CODE
+const (
+	maxExitCode      = 255
+	nonFatalExitCode = -1
+)
+
+const doc = `report common mistakes made while using khata errors
+
+The analyzer reports:
+  - functions returning both *khata.Khata values and plain errors
+  - discarded results of setter chains starting from a new error or template
+  - Explain(fmt.Sprintf(...)) calls that should use Explainf
+  - constant exit codes out of range 0-255, and SetExitCode(-1) calls that should use SetNonFatal
+  - shared templates (package variables, parameters or fields) mutated outside of init functions and package variable declarations
+  - Is and IsAny calls comparing against freshly constructed errors
+  - SetProperty keys not declared on the template the error was created from`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "khatalint",
+	Doc:      doc,
+	URL:      "https://pkg.go.dev/github.com/cmseguin/khata/khatalint",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	templates := collectTemplates(pass)
+
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			checkMixedReturns(pass, fn.Type, fn.Body)
+		case *ast.FuncLit:
+			checkMixedReturns(pass, fn.Type, fn.Body)
+		}
+	})
+
+	inspect.Preorder([]ast.Node{(*ast.ExprStmt)(nil)}, func(n ast.Node) {
+		checkDiscardedChain(pass, n.(*ast.ExprStmt))
+	})
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		checkExplainSprintf(pass, call)
+		checkExitCode(pass, call)
+		checkFreshComparison(pass, call)
+		checkUndeclaredProperty(pass, call, templates)
+	})
+
+	for _, file := range pass.Files {
+		checkTemplateMutations(pass, file)
+	}
+
+	return nil, nil
+}
+
+// Functions returning error must not mix *khata.Khata results with fresh plain errors
+func checkMixedReturns(pass *analysis.Pass, fnType *ast.FuncType, body *ast.BlockStmt) {
+	if body == nil || fnType.Results == nil || len(fnType.Results.List) == 0 {
+		return
+	}
+
+	results := fnType.Results.List
+	last := pass.TypesInfo.TypeOf(results[len(results)-1].Type)
+
+	if !isErrorInterface(last) {
+		return
+	}
+
+	var khataReturned bool
+	var plainReturns []ast.Expr
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			if len(n.Results) == 0 {
+				return true
+			}
+			result := n.Results[len(n.Results)-1]
+			if isKhata(pass.TypesInfo.TypeOf(result)) {
+				khataReturned = true
+			} else if isFreshError(pass, result) {
+				plainReturns = append(plainReturns, result)
+			}
+		}
+		return true
+	})
+
+	if !khataReturned {
+		return
+	}
+
+	for _, result := range plainReturns {
+		pass.Reportf(result.Pos(), "function returns both *khata.Khata and plain errors; wrap this error with a template")
+	}
+}
+
+// Setter chains return their receiver, discarding them is only a mistake when
+// the chain starts by creating a new error or template that is then lost.
+// Constructors are not setters, a bare constructor call is left alone.
+func checkDiscardedChain(pass *analysis.Pass, stmt *ast.ExprStmt) {
+	call, ok := ast.Unparen(stmt.X).(*ast.CallExpr)
+	if !ok {
+		return
+	}
+
+	discarded := calledFunc(pass, call)
+	if discarded == nil || !isChainFunc(discarded) || isConstructor(discarded) {
+		return
+	}
+
+	for expr := ast.Expr(call); ; {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		if !ok {
+			return
+		}
+
+		fn := calledFunc(pass, call)
+		if fn == nil || !isChainFunc(fn) {
+			return
+		}
+
+		if isConstructor(fn) {
+			pass.Reportf(stmt.Pos(), "result of %s is discarded; the value created by %s is lost", discarded.Name(), describe(fn))
+			return
+		}
+
+		selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+		expr = selector.X
+	}
+}
+
+// Explain(fmt.Sprintf(...)) should be written Explainf(...)
+func checkExplainSprintf(pass *analysis.Pass, call *ast.CallExpr) {
+	fn := calledFunc(pass, call)
+	if !isKhataMethod(fn, "Khata", "Explain") || len(call.Args) != 1 {
+		return
+	}
+
+	inner, ok := ast.Unparen(call.Args[0]).(*ast.CallExpr)
+	if !ok || !isFunc(calledFunc(pass, inner), "fmt", "Sprintf") || inner.Ellipsis.IsValid() {
+		return
+	}
+
+	selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	args := make([]string, len(inner.Args))
+	for i, arg := range inner.Args {
+		args[i] = render(pass.Fset, arg)
+	}
+
+	pass.Report(analysis.Diagnostic{
+		Pos:     call.Args[0].Pos(),
+		End:     call.Args[0].End(),
+		Message: "use Explainf instead of Explain with fmt.Sprintf",
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Replace with Explainf",
+			TextEdits: []analysis.TextEdit{
+				{Pos: selector.Sel.Pos(), End: selector.Sel.End(), NewText: []byte("Explainf")},
+				{Pos: call.Args[0].Pos(), End: call.Args[0].End(), NewText: []byte(strings.Join(args, ", "))},
+			},
+		}},
+	})
+}
+
+// Exit codes are truncated to 8 bits by the operating system, and -1 is the non-fatal marker
+func checkExitCode(pass *analysis.Pass, call *ast.CallExpr) {
+	fn := calledFunc(pass, call)
+	if (!isKhataMethod(fn, "Khata", "SetExitCode") && !isKhataMethod(fn, "KhataTemplate", "SetExitCode")) || len(call.Args) != 1 {
+		return
+	}
+
+	value := pass.TypesInfo.Types[call.Args[0]].Value
+	if value == nil || value.Kind() != constant.Int {
+		return
+	}
+
+	code, ok := constant.Int64Val(value)
+	if !ok {
+		return
+	}
+
+	if code >= 0 && code <= maxExitCode {
+		return
+	}
+
+	if code != nonFatalExitCode {
+		pass.Reportf(call.Args[0].Pos(), "exit code %d is out of range 0-%d", code, maxExitCode)
+		return
+	}
+
+	selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	pass.Report(analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: "use SetNonFatal instead of SetExitCode(-1)",
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Replace with SetNonFatal",
+			TextEdits: []analysis.TextEdit{
+				{Pos: selector.Sel.Pos(), End: call.End(), NewText: []byte("SetNonFatal()")},
+			},
+		}},
+	})
+}
+
+// Is and IsAny compare errors by identity, so a freshly constructed error never matches
+func checkFreshComparison(pass *analysis.Pass, call *ast.CallExpr) {
+	fn := calledFunc(pass, call)
+	if !isKhataMethod(fn, "Khata", "Is") && !isKhataMethod(fn, "Khata", "IsAny") {
+		return
+	}
+
+	for _, arg := range call.Args {
+		if isFreshError(pass, arg) {
+			pass.Reportf(arg.Pos(), "%s compares against a freshly constructed error that can never match; compare against a sentinel error variable", fn.Name())
+		}
+	}
+}
+
+// Templates are shared by every error created from them, they should only be
+// configured while the program initializes.
+func checkTemplateMutations(pass *analysis.Pass, file *ast.File) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || (fn.Recv == nil && fn.Name.Name == "init") {
+			continue
+		}
+
+		params := funcParams(pass, fn)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			fn := calledFunc(pass, call)
+			if fn == nil || !isTemplateMutator(fn) {
+				return true
+			}
+
+			// Setting up a template created in the function is fine, only shared templates matter
+			selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+			if !ok || !isSharedTemplate(pass, selector.X, params) {
+				return true
+			}
+
+			pass.Reportf(call.Pos(), "template mutated by %s outside of init; configure templates in package variables or init functions", fn.Name())
+			return true
+		})
+	}
+}
+
+// Properties set on errors created from a template declaring properties must be declared on it
+func checkUndeclaredProperty(pass *analysis.Pass, call *ast.CallExpr, templates *templateIndex) {
+	fn := calledFunc(pass, call)
+	if !isKhataMethod(fn, "Khata", "SetProperty") || len(call.Args) != 2 {
+		return
+	}
+
+	key, ok := constantString(pass, call.Args[0])
+	if !ok {
+		return
+	}
+
+	selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+
+	template := templates.origin(pass, selector.X)
+	if template == nil {
+		return
+	}
+
+	keys := templates.keys(template)
+	if len(keys) == 0 || keys[key] {
+		return
+	}
+
+	pass.Reportf(call.Args[0].Pos(), "property %q is not declared on template %s", key, template.Name())
+}
+
+// Index of the package level templates and the property keys they declare
+type templateIndex struct {
+	pass *analysis.Pass
+	// Expression each template variable is initialized with
+	initializers map[*types.Var]ast.Expr
+	// Property keys set on each template variable in init functions
+	initKeys map[*types.Var]map[string]bool
+	// Template each local variable was created from with New or Wrap
+	locals   map[*types.Var]*types.Var
+	resolved map[*types.Var]map[string]bool
+}
+
+func collectTemplates(pass *analysis.Pass) *templateIndex {
+	index := &templateIndex{
+		pass:         pass,
+		initializers: map[*types.Var]ast.Expr{},
+		initKeys:     map[*types.Var]map[string]bool{},
+		locals:       map[*types.Var]*types.Var{},
+		resolved:     map[*types.Var]map[string]bool{},
+	}
+
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					for i, name := range spec.Names {
+						v, ok := pass.TypesInfo.Defs[name].(*types.Var)
+						if ok && i < len(spec.Values) && isTemplate(v.Type()) {
+							index.initializers[v] = spec.Values[i]
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Name.Name == "init" && decl.Body != nil {
+					index.collectInitKeys(pass, decl.Body)
+				}
+			}
+		}
+	}
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if len(n.Lhs) == len(n.Rhs) {
+					for i := range n.Lhs {
+						index.recordLocal(pass, n.Lhs[i], n.Rhs[i])
+					}
+				}
+			case *ast.ValueSpec:
+				if len(n.Names) == len(n.Values) {
+					for i := range n.Names {
+						index.recordLocal(pass, n.Names[i], n.Values[i])
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	return index
+}
+
+func (index *templateIndex) collectInitKeys(pass *analysis.Pass, body *ast.BlockStmt) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isKhataMethod(calledFunc(pass, call), "KhataTemplate", "SetProperty") || len(call.Args) != 2 {
+			return true
+		}
+
+		key, ok := constantString(pass, call.Args[0])
+		if !ok {
+			return true
+		}
+
+		selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if v := chainRootVar(pass, selector.X); v != nil {
+			if index.initKeys[v] == nil {
+				index.initKeys[v] = map[string]bool{}
+			}
+			index.initKeys[v][key] = true
+		}
+		return true
+	})
+}
+
+func (index *templateIndex) recordLocal(pass *analysis.Pass, lhs ast.Expr, rhs ast.Expr) {
+	ident, ok := lhs.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	obj := pass.TypesInfo.ObjectOf(ident)
+	v, ok := obj.(*types.Var)
+	if !ok || !isKhata(v.Type()) {
+		return
+	}
+
+	if template := index.origin(pass, rhs); template != nil {
+		index.locals[v] = template
+	}
+}
+
+// Returns the template variable the error expression was created from, if it can be determined
+func (index *templateIndex) origin(pass *analysis.Pass, expr ast.Expr) *types.Var {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.Ident:
+			v, _ := pass.TypesInfo.ObjectOf(e).(*types.Var)
+			return index.locals[v]
+		case *ast.CallExpr:
+			fn := calledFunc(pass, e)
+			selector, ok := ast.Unparen(e.Fun).(*ast.SelectorExpr)
+			if fn == nil || !ok {
+				return nil
+			}
+			if isKhataMethod(fn, "KhataTemplate", "New") || isKhataMethod(fn, "KhataTemplate", "Wrap") {
+				ident, ok := ast.Unparen(selector.X).(*ast.Ident)
+				if !ok {
+					return nil
+				}
+				v, _ := pass.TypesInfo.ObjectOf(ident).(*types.Var)
+				if _, known := index.initializers[v]; !known {
+					return nil
+				}
+				return v
+			}
+			if !isChainFunc(fn) {
+				return nil
+			}
+			expr = selector.X
+		default:
+			return nil
+		}
+	}
+}
+
+// Returns the property keys declared on the template and its parents
+func (index *templateIndex) keys(template *types.Var) map[string]bool {
+	if keys, ok := index.resolved[template]; ok {
+		return keys
+	}
+
+	keys := map[string]bool{}
+	// Guards against initialization cycles
+	index.resolved[template] = keys
+
+	for key := range index.initKeys[template] {
+		keys[key] = true
+	}
+
+	for expr := index.initializers[template]; expr != nil; {
+		call, ok := ast.Unparen(expr).(*ast.CallExpr)
+		if !ok {
+			break
+		}
+
+		selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		fn := calledFunc(index.pass, call)
+		if !ok || fn == nil {
+			break
+		}
+
+		if isKhataMethod(fn, "KhataTemplate", "SetProperty") && len(call.Args) == 2 {
+			if key, ok := constantString(index.pass, call.Args[0]); ok {
+				keys[key] = true
+			}
+		}
+
+		if isKhataMethod(fn, "KhataTemplate", "Extend") {
+			if ident, ok := ast.Unparen(selector.X).(*ast.Ident); ok {
+				if parent, ok := index.pass.TypesInfo.ObjectOf(ident).(*types.Var); ok {
+					for key := range index.keys(parent) {
+						keys[key] = true
+					}
+				}
+			}
+			break
+		}
+
+		expr = selector.X
+	}
+
+	return keys
+}
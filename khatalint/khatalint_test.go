@@ -0,0 +1,12 @@
+package khatalint_test
+
+import (
+	"testing"
+
+	"github.com/cmseguin/khata/khatalint"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), khatalint.Analyzer, "a")
+}
@@ -0,0 +1,89 @@
+package a
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cmseguin/khata"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var HttpError = khata.NewTemplate().SetType("HTTP").SetProperty("path", "")
+
+var NotFound = HttpError.Extend().SetCode(404).SetProperty("id", 0)
+
+var Untyped = khata.NewTemplate()
+
+var Configured = khata.NewTemplate()
+
+func init() {
+	Configured.SetProperty("retryable", false)
+	HttpError.SetCode(500)
+}
+
+func mixedReturns(ok bool) error {
+	if ok {
+		return NotFound.New()
+	}
+	return errors.New("plain") // want `function returns both \*khata.Khata and plain errors; wrap this error with a template`
+}
+
+func onlyPlain() error {
+	return fmt.Errorf("plain")
+}
+
+func discarded() {
+	khata.New("lost")
+	NotFound.New()
+	NotFound.New().SetCode(1)            // want `result of SetCode is discarded; the value created by KhataTemplate.New is lost`
+	NotFound.Extend().SetType("Nothing") // want `result of SetType is discarded; the value created by KhataTemplate.Extend is lost`
+
+	k := NotFound.New()
+	k.SetCode(2)
+	khata.New("printed").Debug()
+}
+
+func explain(k *khata.Khata, id int) {
+	k.Explain(fmt.Sprintf("user %d not found", id)) // want `use Explainf instead of Explain with fmt.Sprintf`
+	k.Explain("static")
+}
+
+type server struct {
+	errors *khata.KhataTemplate
+}
+
+func mutate(s *server, shared *khata.KhataTemplate) {
+	HttpError.SetType("Other") // want `template mutated by SetType outside of init; configure templates in package variables or init functions`
+	local := khata.NewTemplate().SetCode(1)
+	local.SetType("Local")
+	extended := NotFound.Extend()
+	extended.SetCode(410).SetProperty("gone", true)
+	shared.SetCode(400)   // want `template mutated by SetCode outside of init; configure templates in package variables or init functions`
+	s.errors.SetCode(503) // want `template mutated by SetCode outside of init; configure templates in package variables or init functions`
+}
+
+func compare(k *khata.Khata) bool {
+	return k.Is(errors.New("not found")) || // want `Is compares against a freshly constructed error that can never match; compare against a sentinel error variable`
+		k.IsAny(ErrNotFound, fmt.Errorf("other")) || // want `IsAny compares against a freshly constructed error that can never match; compare against a sentinel error variable`
+		k.Is(ErrNotFound)
+}
+
+func properties() *khata.Khata {
+	k := NotFound.New().SetProperty("path", "/users")
+	k.SetProperty("id", 12)
+	k.SetProperty("userID", 12) // want `property "userID" is not declared on template NotFound`
+
+	_ = Configured.New().SetProperty("retryable", true).SetProperty("attempt", 1) // want `property "attempt" is not declared on template Configured`
+
+	return Untyped.New().SetProperty("anything", 1)
+}
+
+var Quiet = khata.NewTemplate().SetExitCode(-1) // want `use SetNonFatal instead of SetExitCode\(-1\)`
+
+func exitCodes(k *khata.Khata) *khata.Khata {
+	const tooLarge = 300
+	k.SetExitCode(64)
+	k.SetExitCode(tooLarge)  // want `exit code 300 is out of range 0-255`
+	return k.SetExitCode(-1) // want `use SetNonFatal instead of SetExitCode\(-1\)`
+}
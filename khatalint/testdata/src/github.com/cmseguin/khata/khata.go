@@ -0,0 +1,46 @@
+// Package khata is a minimal stub of the khata API used by the analyzer tests.
+package khata
+
+type Khata struct{ Err error }
+
+func (k *Khata) Error() string                                    { return "" }
+func (k *Khata) Is(err error) bool                                { return false }
+func (k *Khata) IsAny(errs ...error) bool                         { return false }
+func (k *Khata) SetCode(code int) *Khata                          { return k }
+func (k *Khata) SetType(errorType string) *Khata                  { return k }
+func (k *Khata) SetExitCode(code int) *Khata                      { return k }
+func (k *Khata) SetNonFatal() *Khata                              { return k }
+func (k *Khata) SetProperty(key string, value interface{}) *Khata { return k }
+func (k *Khata) Explain(explanation string) *Khata                { return k }
+func (k *Khata) Explainf(format string, args ...interface{}) *Khata {
+	return k
+}
+func (k *Khata) Debug() *Khata { return k }
+
+type KhataTemplate struct{}
+
+func (kt *KhataTemplate) New(message ...string) *Khata { return nil }
+func (kt *KhataTemplate) Wrap(err error) *Khata        { return nil }
+func (kt *KhataTemplate) Extend() *KhataTemplate       { return kt }
+func (kt *KhataTemplate) SetCode(code int) *KhataTemplate {
+	return kt
+}
+func (kt *KhataTemplate) SetType(errorType string) *KhataTemplate {
+	return kt
+}
+func (kt *KhataTemplate) SetExitCode(code int) *KhataTemplate {
+	return kt
+}
+func (kt *KhataTemplate) SetNonFatal() *KhataTemplate {
+	return kt
+}
+func (kt *KhataTemplate) SetProperty(key string, value interface{}) *KhataTemplate {
+	return kt
+}
+func (kt *KhataTemplate) RemoveProperty(key string) *KhataTemplate {
+	return kt
+}
+
+func New(message string) *Khata   { return nil }
+func Wrap(err error) *Khata       { return nil }
+func NewTemplate() *KhataTemplate { return nil }
@@ -0,0 +1,61 @@
+package khatametrics
+
+import (
+	"expvar"
+	"strings"
+
+	"github.com/cmseguin/khata"
+)
+
+// Expvar publishes the number of created and handled errors, and the time it took
+// to handle them, with expvar. It is a khata.Observer:
+//
+//	labeler := khatametrics.NewLabeler().RegisterTemplate("not_found", NotFound)
+//	khata.AddObserver(khatametrics.NewExpvar("khata", labeler))
+//
+// Every counter is keyed by its labels, like "type=HTTP,code=404,template=not_found,severity=error".
+type Expvar struct {
+	labeler *Labeler
+	created *expvar.Map
+	handled *expvar.Map
+	latency *expvar.Map
+}
+
+// Create the expvar exporter and publish it under the given name.
+// Like expvar.Publish, it panics if the name is already used.
+func NewExpvar(name string, labeler *Labeler) *Expvar {
+	e := &Expvar{
+		labeler: labeler,
+		created: new(expvar.Map),
+		handled: new(expvar.Map),
+		latency: new(expvar.Map),
+	}
+
+	root := expvar.NewMap(name)
+	root.Set("created", e.created)
+	root.Set("handled", e.handled)
+	root.Set("handlingSecondsSum", e.latency)
+
+	return e
+}
+
+func (e *Expvar) ErrorCreated(k *khata.Khata) {
+	e.created.Add(e.key(k), 1)
+}
+
+func (e *Expvar) ErrorHandled(k *khata.Khata) {
+	key := e.key(k)
+	e.handled.Add(key, 1)
+	e.latency.AddFloat(key, k.HandledAt().Sub(k.CreatedAt()).Seconds())
+}
+
+func (e *Expvar) key(k *khata.Khata) string {
+	values := e.labeler.Labels(k).Values()
+	pairs := make([]string, len(values))
+
+	for i, value := range values {
+		pairs[i] = LabelNames[i] + "=" + value
+	}
+
+	return strings.Join(pairs, ",")
+}
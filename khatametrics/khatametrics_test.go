@@ -0,0 +1,74 @@
+package khatametrics_test
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatametrics"
+)
+
+func TestLabels(t *testing.T) {
+	http := khata.NewTemplate().SetType("HTTP").SetCode(500)
+	notFound := http.Extend().SetCode(404)
+	userNotFound := notFound.Extend().SetMessage("User not found")
+
+	labeler := khatametrics.NewLabeler().
+		RegisterTemplate("not_found", notFound).
+		RegisterCodes(400)
+
+	labels := labeler.Labels(userNotFound.New())
+
+	if labels != (khatametrics.Labels{Type: "HTTP", Code: "404", Template: "not_found", Severity: "fatal"}) {
+		t.Error("Labels() did not use the closest registered template")
+		return
+	}
+
+	labels = labeler.Labels(khata.New("This is an error message").SetType("Database").SetCode(1045))
+
+	if labels != (khatametrics.Labels{Type: khatametrics.OTHER, Code: khatametrics.OTHER, Template: khatametrics.NO_TEMPLATE, Severity: "fatal"}) {
+		t.Error("Labels() did not bucket unregistered values")
+		return
+	}
+
+	http.SetType("Web")
+	notFound.SetCode(410)
+
+	if labels := labeler.Labels(userNotFound.New()); labels.Type != "Web" || labels.Code != "410" {
+		t.Error("Labels() did not resolve the type and code of the registered template")
+		return
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	current := time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)
+	khata.SetClock(khata.ClockFunc(func() time.Time { return current }))
+	defer khata.SetClock(nil)
+
+	notFound := khata.NewTemplate().SetType("HTTP").SetCode(404)
+	exporter := khatametrics.NewExpvar("khata_test", khatametrics.NewLabeler().RegisterTemplate("not_found", notFound))
+	defer khata.AddObserver(exporter)()
+
+	k := notFound.New()
+	current = current.Add(2 * time.Second)
+	k.MarkHandled()
+
+	key := "type=HTTP,code=404,template=not_found,severity=fatal"
+	root := expvar.Get("khata_test").(*expvar.Map)
+
+	if root.Get("created").(*expvar.Map).Get(key).String() != "1" {
+		t.Error("NewExpvar() did not count the created error")
+		return
+	}
+
+	if root.Get("handled").(*expvar.Map).Get(key).String() != "1" {
+		t.Error("NewExpvar() did not count the handled error")
+		return
+	}
+
+	if root.Get("handlingSecondsSum").(*expvar.Map).Get(key).String() != "2" {
+		t.Error("NewExpvar() did not sum the handling latency")
+		return
+	}
+}
@@ -0,0 +1,148 @@
+// Package khatametrics counts khata errors with bounded labels. It provides the
+// labeling shared by the metrics integrations and an expvar exporter without
+// dependencies.
+package khatametrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/cmseguin/khata"
+)
+
+// Label value used for types and codes that were not registered
+const OTHER = "other"
+
+// Label value used for errors not created from a registered template
+const NO_TEMPLATE = "none"
+
+// Labels of an error. Every value is bounded by what was registered on the Labeler.
+type Labels struct {
+	Type     string
+	Code     string
+	Template string
+	Severity string
+}
+
+// Returns the labels in the order of LabelNames
+func (l Labels) Values() []string {
+	return []string{l.Type, l.Code, l.Template, l.Severity}
+}
+
+// Names of the labels, in the order of Labels.Values
+var LabelNames = []string{"type", "code", "template", "severity"}
+
+// Labeler computes the labels of errors. Templates, types and codes must be
+// registered up front, everything else is bucketed to keep the cardinality bounded.
+type Labeler struct {
+	mutex     sync.RWMutex
+	templates map[*khata.KhataTemplate]string
+	types     map[string]bool
+	codes     map[int]bool
+}
+
+// Create an empty labeler
+func NewLabeler() *Labeler {
+	return &Labeler{
+		templates: map[*khata.KhataTemplate]string{},
+		types:     map[string]bool{},
+		codes:     map[int]bool{},
+	}
+}
+
+// Register a template under the given name. Errors created from the template
+// or its children get the name as template label, unless a child is registered too.
+// The type and code of the template are label values as well, resolved when
+// errors are labeled so they follow the changes of its parents.
+func (l *Labeler) RegisterTemplate(name string, template *khata.KhataTemplate) *Labeler {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.templates[template] = name
+
+	return l
+}
+
+// Register error types used as label values
+func (l *Labeler) RegisterTypes(types ...string) *Labeler {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, t := range types {
+		l.types[t] = true
+	}
+
+	return l
+}
+
+// Register error codes used as label values
+func (l *Labeler) RegisterCodes(codes ...int) *Labeler {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, code := range codes {
+		l.codes[code] = true
+	}
+
+	return l
+}
+
+// Returns the labels of the error
+func (l *Labeler) Labels(k *khata.Khata) Labels {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	labels := Labels{
+		Type:     OTHER,
+		Code:     OTHER,
+		Template: NO_TEMPLATE,
+		Severity: k.Severity().String(),
+	}
+
+	if l.knownType(k.Type()) {
+		labels.Type = k.Type()
+	}
+
+	if l.knownCode(k.Code()) {
+		labels.Code = strconv.Itoa(k.Code())
+	}
+
+	if template := k.Template(); template != nil {
+		for _, t := range append([]*khata.KhataTemplate{template}, template.Ancestors()...) {
+			if name, ok := l.templates[t]; ok {
+				labels.Template = name
+				break
+			}
+		}
+	}
+
+	return labels
+}
+
+// Returns true if the type was registered, or is the type of a registered template
+func (l *Labeler) knownType(errorType string) bool {
+	if l.types[errorType] {
+		return true
+	}
+
+	for template := range l.templates {
+		if template.Type() == errorType {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the code was registered, or is the code of a registered template
+func (l *Labeler) knownCode(code int) bool {
+	if l.codes[code] {
+		return true
+	}
+
+	for template := range l.templates {
+		if template.Code() == code {
+			return true
+		}
+	}
+	return false
+}
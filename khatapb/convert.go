@@ -0,0 +1,250 @@
+// Package khatapb defines the protobuf representation of khata errors, for
+// channels where JSON is too heavy, and the conversions from and to khata errors.
+//
+// The Go code is generated from khata.proto:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative khata.proto
+package khatapb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cmseguin/khata"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative khata.proto
+
+// Converts a khata error and its causes to its protobuf representation.
+// The trace is computed at the time of calling and filtered, like ToJSON does,
+// and the handling time is only set if the error was marked as handled.
+// Property and field values are converted like encoding/json would, and values that
+// cannot be marshaled are formatted with %v.
+func ToProto(k *khata.Khata) (*Error, error) {
+	e, err := toProtoLayer(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if handledAt := k.HandledAt(); !handledAt.IsZero() {
+		e.HandledAt = timestamppb.New(handledAt)
+	}
+
+	e.Trace = toProtoFrames(k.Trace())
+
+	for _, group := range k.Goroutines() {
+		e.Goroutines = append(e.Goroutines, &GoroutineGroup{
+			Ids:   group.IDs,
+			State: group.State,
+			Trace: toProtoFrames(group.Trace),
+		})
+	}
+
+	return e, nil
+}
+
+func toProtoFrames(trace []khata.KhataTrace) []*Frame {
+	var frames []*Frame
+	for _, t := range khata.FilterTrace(trace) {
+		frames = append(frames, &Frame{
+			File:         t.File(),
+			Line:         int32(t.Line()),
+			FunctionName: t.FunctionName(),
+			Folded:       int32(t.Folded()),
+			OwnCode:      t.IsOwnCode(),
+		})
+	}
+	return frames
+}
+
+func toProtoLayer(k *khata.Khata) (*Error, error) {
+	properties, err := toStruct(k.SerializedProperties())
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Error{
+		Message:     k.Err.Error(),
+		Type:        k.Type(),
+		Code:        int64(k.Code()),
+		CodeName:    k.CodeName(),
+		ExitCode:    int32(k.ExitCode()),
+		NonFatal:    k.IsNonFatal(),
+		Severity:    Severity(k.Severity()),
+		CreatedAt:   timestamppb.New(k.CreatedAt()),
+		Properties:  properties,
+		Environment: toProtoEnvironment(k.Environment()),
+	}
+
+	for _, explanation := range k.Explanations() {
+		var fields *structpb.Struct
+
+		if len(explanation.Fields) != 0 {
+			if fields, err = toStruct(explanation.Fields); err != nil {
+				return nil, err
+			}
+		}
+
+		e.Explanations = append(e.Explanations, &Explanation{
+			Message:      explanation.Message,
+			File:         explanation.File,
+			Line:         int32(explanation.Line),
+			FunctionName: explanation.FunctionName,
+			Fields:       fields,
+			Elapsed:      durationpb.New(explanation.Elapsed),
+			Goroutine:    explanation.Goroutine,
+		})
+	}
+
+	if cause := k.Cause(); cause != nil {
+		if e.Cause, err = toProtoLayer(cause); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func toProtoEnvironment(env *khata.KhataEnvironment) *Environment {
+	if env == nil {
+		return nil
+	}
+
+	return &Environment{
+		Goroutine:     env.Goroutine,
+		Labels:        env.Labels,
+		Hostname:      env.Hostname,
+		Pid:           int64(env.PID),
+		Module:        env.Module,
+		ModuleVersion: env.ModuleVersion,
+		GoVersion:     env.GoVersion,
+		VcsRevision:   env.VCSRevision,
+		VcsTime:       env.VCSTime,
+		VcsModified:   env.VCSModified,
+	}
+}
+
+// Rebuilds a khata error and its causes from its protobuf representation
+func FromProto(e *Error) *khata.Khata {
+	return khata.Restore(toRecord(e))
+}
+
+func toRecord(e *Error) *khata.KhataRecord {
+	record := &khata.KhataRecord{
+		Message:     e.GetMessage(),
+		Type:        e.GetType(),
+		Code:        int(e.GetCode()),
+		CodeName:    e.GetCodeName(),
+		ExitCode:    int(e.GetExitCode()),
+		NonFatal:    e.GetNonFatal(),
+		Severity:    khata.Severity(e.GetSeverity()),
+		Properties:  e.GetProperties().AsMap(),
+		Environment: fromProtoEnvironment(e.GetEnvironment()),
+	}
+
+	if e.CreatedAt != nil {
+		record.CreatedAt = e.CreatedAt.AsTime()
+	}
+
+	if e.HandledAt != nil {
+		record.HandledAt = e.HandledAt.AsTime()
+	}
+
+	for _, explanation := range e.GetExplanations() {
+		var fields map[string]interface{}
+
+		if explanation.Fields != nil {
+			fields = explanation.Fields.AsMap()
+		}
+
+		record.Explanations = append(record.Explanations, khata.KhataExplanation{
+			Message:      explanation.GetMessage(),
+			File:         explanation.GetFile(),
+			Line:         int(explanation.GetLine()),
+			FunctionName: explanation.GetFunctionName(),
+			Fields:       fields,
+			Elapsed:      explanation.GetElapsed().AsDuration(),
+			Goroutine:    explanation.GetGoroutine(),
+		})
+	}
+
+	record.Trace = fromProtoFrames(e.GetTrace())
+
+	for _, group := range e.GetGoroutines() {
+		record.Goroutines = append(record.Goroutines, khata.KhataGoroutineGroup{
+			IDs:   group.GetIds(),
+			State: group.GetState(),
+			Trace: fromProtoFrames(group.GetTrace()),
+		})
+	}
+
+	if e.Cause != nil {
+		record.Cause = toRecord(e.Cause)
+	}
+
+	return record
+}
+
+// Own code flags are not decoded, they are computed from the main module of the program
+func fromProtoFrames(frames []*Frame) []khata.KhataTrace {
+	var trace []khata.KhataTrace
+	for _, frame := range frames {
+		trace = append(trace, khata.NewFoldedKhataTrace(frame.GetFile(), int(frame.GetLine()), frame.GetFunctionName(), int(frame.GetFolded())))
+	}
+	return trace
+}
+
+func fromProtoEnvironment(env *Environment) *khata.KhataEnvironment {
+	if env == nil {
+		return nil
+	}
+
+	return &khata.KhataEnvironment{
+		Goroutine:     env.GetGoroutine(),
+		Labels:        env.GetLabels(),
+		Hostname:      env.GetHostname(),
+		PID:           int(env.GetPid()),
+		Module:        env.GetModule(),
+		ModuleVersion: env.GetModuleVersion(),
+		GoVersion:     env.GetGoVersion(),
+		VCSRevision:   env.GetVcsRevision(),
+		VCSTime:       env.GetVcsTime(),
+		VCSModified:   env.GetVcsModified(),
+	}
+}
+
+func toStruct(values map[string]interface{}) (*structpb.Struct, error) {
+	fields := make(map[string]*structpb.Value, len(values))
+
+	for key, value := range values {
+		converted, err := toValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("khatapb: cannot convert %q: %w", key, err)
+		}
+		fields[key] = converted
+	}
+
+	return &structpb.Struct{Fields: fields}, nil
+}
+
+// Converts the value the way encoding/json would, so both representations carry the same data
+func toValue(value interface{}) (*structpb.Value, error) {
+	if converted, err := structpb.NewValue(value); err == nil {
+		return converted, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return structpb.NewStringValue(fmt.Sprintf("%v", value)), nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	return structpb.NewValue(decoded)
+}
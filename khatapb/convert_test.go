@@ -0,0 +1,132 @@
+package khatapb_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatapb"
+	"google.golang.org/protobuf/proto"
+)
+
+type fixedStack struct{}
+
+func (fixedStack) Trace() []khata.KhataTrace {
+	return []khata.KhataTrace{
+		khata.NewKhataTrace("/app/tree.go", 12, "app.walk"),
+		khata.NewKhataTrace("/app/tree.go", 15, "app.walk"),
+		khata.NewKhataTrace("/app/handler.go", 42, "github.com/cmseguin/khata/app.Handle"),
+		khata.NewKhataTrace("/app/main.go", 10, "main.main"),
+	}
+}
+
+func (fixedStack) Caller() khata.KhataTrace {
+	return khata.NewKhataTrace("/app/handler.go", 40, "app.Handle")
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+var tokenKey = khata.NewKey[string]("token", khata.Redacted())
+
+func newError() *khata.Khata {
+	inner := khata.New("connection refused").
+		SetType("Database").
+		SetSeverity(khata.SeverityCritical).
+		SetCodeName("DB.CONNECTION.REFUSED").
+		ExplainWith("This is an explanation of the database", "attempt", 3, "tags", []string{"a", "b"})
+
+	k := khata.Wrap(inner).
+		SetType("HTTP").
+		SetCode(503).
+		SetNonFatal().
+		SetProperty("user", user{ID: 12, Name: "Ada"}).
+		SetProperty("createdAt", time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)).
+		SetProperty("callback", func() {}).
+		SetProperty("nothing", nil).
+		Explain("This is an explanation of the request")
+
+	tokenKey.Set(k, "secret")
+
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "42"))
+	return k.CaptureEnvironment(ctx).AttachGoroutineDump().MarkHandled()
+}
+
+func TestRoundTrip(t *testing.T) {
+	khata.SetStackCapture(fixedStack{})
+	defer khata.SetStackCapture(nil)
+
+	config := khata.CurrentConfig()
+	defer khata.SetConfig(config)
+	folding := config
+	folding.FoldRecursion = true
+	khata.SetConfig(folding)
+
+	k := newError()
+
+	e, err := khatapb.ToProto(k)
+	if err != nil {
+		t.Fatalf("ToProto() returned an error: %s", err)
+	}
+
+	data, err := proto.Marshal(e)
+	if err != nil {
+		t.Fatalf("proto.Marshal() returned an error: %s", err)
+	}
+
+	var decoded khatapb.Error
+	if err := proto.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("proto.Unmarshal() returned an error: %s", err)
+	}
+
+	if len(decoded.Trace) != 3 || decoded.Trace[0].GetFolded() != 1 || !decoded.Trace[1].GetOwnCode() || decoded.Trace[0].GetOwnCode() {
+		t.Error("ToProto() did not encode the folded frames and the own code flags")
+		return
+	}
+
+	fromProto := khatapb.FromProto(&decoded)
+
+	fromJSON, err := khata.FromJSON([]byte(k.ToJSON()))
+	if err != nil {
+		t.Fatalf("FromJSON() returned an error: %s", err)
+	}
+
+	if fromProto.ToJSON() != fromJSON.ToJSON() {
+		t.Errorf("the protobuf and JSON round trips are different:\nprotobuf: %s\njson:     %s", fromProto.ToJSON(), fromJSON.ToJSON())
+		return
+	}
+
+	if fromProto.Cause() == nil || fromProto.Cause().Severity() != khata.SeverityCritical {
+		t.Error("FromProto() did not restore the cause")
+		return
+	}
+
+	if fromProto.GetProperty("token") != khata.REDACTED_VALUE {
+		t.Error("ToProto() did not redact the property")
+		return
+	}
+
+	if !decoded.GetNonFatal() || decoded.GetExitCode() != khata.DEFAULT_EXIT_CODE || !fromProto.IsNonFatal() {
+		t.Error("ToProto() did not carry the non-fatal flag apart from the exit code")
+		return
+	}
+
+	if fromProto.Cause().CodeName() != "DB.CONNECTION.REFUSED" {
+		t.Error("FromProto() did not restore the code name")
+		return
+	}
+
+	if env := fromProto.Environment(); env == nil || env.Labels["request"] != "42" || env.PID == 0 {
+		t.Error("FromProto() did not restore the environment")
+		return
+	}
+
+	if len(fromProto.Goroutines()) == 0 || len(fromProto.Goroutines()) != len(k.Goroutines()) {
+		t.Error("FromProto() did not restore the goroutine dump")
+		return
+	}
+}
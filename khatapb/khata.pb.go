@@ -0,0 +1,749 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: khata.proto
+
+package khatapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Severity int32
+
+const (
+	Severity_SEVERITY_UNSPECIFIED Severity = 0
+	Severity_SEVERITY_DEBUG       Severity = 1
+	Severity_SEVERITY_INFO        Severity = 2
+	Severity_SEVERITY_WARNING     Severity = 3
+	Severity_SEVERITY_ERROR       Severity = 4
+	Severity_SEVERITY_CRITICAL    Severity = 5
+	Severity_SEVERITY_FATAL       Severity = 6
+)
+
+// Enum value maps for Severity.
+var (
+	Severity_name = map[int32]string{
+		0: "SEVERITY_UNSPECIFIED",
+		1: "SEVERITY_DEBUG",
+		2: "SEVERITY_INFO",
+		3: "SEVERITY_WARNING",
+		4: "SEVERITY_ERROR",
+		5: "SEVERITY_CRITICAL",
+		6: "SEVERITY_FATAL",
+	}
+	Severity_value = map[string]int32{
+		"SEVERITY_UNSPECIFIED": 0,
+		"SEVERITY_DEBUG":       1,
+		"SEVERITY_INFO":        2,
+		"SEVERITY_WARNING":     3,
+		"SEVERITY_ERROR":       4,
+		"SEVERITY_CRITICAL":    5,
+		"SEVERITY_FATAL":       6,
+	}
+)
+
+func (x Severity) Enum() *Severity {
+	p := new(Severity)
+	*p = x
+	return p
+}
+
+func (x Severity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Severity) Descriptor() protoreflect.EnumDescriptor {
+	return file_khata_proto_enumTypes[0].Descriptor()
+}
+
+func (Severity) Type() protoreflect.EnumType {
+	return &file_khata_proto_enumTypes[0]
+}
+
+func (x Severity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Severity.Descriptor instead.
+func (Severity) EnumDescriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{0}
+}
+
+// Error is the wire representation of a khata error.
+// It carries the same information as the JSON representation.
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Message of the wrapped error.
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Type    string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Code    int64  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	// Exit code of the error. -1 is decoded as non_fatal, for the messages
+	// encoded before non_fatal existed.
+	ExitCode  int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Severity  Severity               `protobuf:"varint,5,opt,name=severity,proto3,enum=khata.v1.Severity" json:"severity,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Only set on the outermost error of the chain.
+	HandledAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=handled_at,json=handledAt,proto3" json:"handled_at,omitempty"`
+	Explanations []*Explanation         `protobuf:"bytes,8,rep,name=explanations,proto3" json:"explanations,omitempty"`
+	// Only set on the outermost error of the chain.
+	Trace      []*Frame         `protobuf:"bytes,9,rep,name=trace,proto3" json:"trace,omitempty"`
+	Properties *structpb.Struct `protobuf:"bytes,10,opt,name=properties,proto3" json:"properties,omitempty"`
+	// The khata error wrapped by this one.
+	Cause *Error `protobuf:"bytes,11,opt,name=cause,proto3" json:"cause,omitempty"`
+	// Name of the code, like AUTH.TOKEN.EXPIRED.
+	CodeName string `protobuf:"bytes,12,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
+	// Goroutine and process context, unset when it was not captured.
+	Environment *Environment `protobuf:"bytes,13,opt,name=environment,proto3" json:"environment,omitempty"`
+	// Goroutine dump, only set on the outermost error of the chain.
+	Goroutines []*GoroutineGroup `protobuf:"bytes,14,rep,name=goroutines,proto3" json:"goroutines,omitempty"`
+	// Whether the error does not exit the program.
+	NonFatal      bool `protobuf:"varint,15,opt,name=non_fatal,json=nonFatal,proto3" json:"non_fatal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_khata_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_khata_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Error) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Error) GetCode() int64 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Error) GetSeverity() Severity {
+	if x != nil {
+		return x.Severity
+	}
+	return Severity_SEVERITY_UNSPECIFIED
+}
+
+func (x *Error) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Error) GetHandledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.HandledAt
+	}
+	return nil
+}
+
+func (x *Error) GetExplanations() []*Explanation {
+	if x != nil {
+		return x.Explanations
+	}
+	return nil
+}
+
+func (x *Error) GetTrace() []*Frame {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+func (x *Error) GetProperties() *structpb.Struct {
+	if x != nil {
+		return x.Properties
+	}
+	return nil
+}
+
+func (x *Error) GetCause() *Error {
+	if x != nil {
+		return x.Cause
+	}
+	return nil
+}
+
+func (x *Error) GetCodeName() string {
+	if x != nil {
+		return x.CodeName
+	}
+	return ""
+}
+
+func (x *Error) GetEnvironment() *Environment {
+	if x != nil {
+		return x.Environment
+	}
+	return nil
+}
+
+func (x *Error) GetGoroutines() []*GoroutineGroup {
+	if x != nil {
+		return x.Goroutines
+	}
+	return nil
+}
+
+func (x *Error) GetNonFatal() bool {
+	if x != nil {
+		return x.NonFatal
+	}
+	return false
+}
+
+type Explanation struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Message      string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	File         string                 `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Line         int32                  `protobuf:"varint,3,opt,name=line,proto3" json:"line,omitempty"`
+	FunctionName string                 `protobuf:"bytes,4,opt,name=function_name,json=functionName,proto3" json:"function_name,omitempty"`
+	Fields       *structpb.Struct       `protobuf:"bytes,5,opt,name=fields,proto3" json:"fields,omitempty"`
+	// Time elapsed between the creation of the error and the explanation.
+	Elapsed *durationpb.Duration `protobuf:"bytes,6,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	// ID of the goroutine that added the explanation.
+	Goroutine     uint64 `protobuf:"varint,7,opt,name=goroutine,proto3" json:"goroutine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Explanation) Reset() {
+	*x = Explanation{}
+	mi := &file_khata_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Explanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Explanation) ProtoMessage() {}
+
+func (x *Explanation) ProtoReflect() protoreflect.Message {
+	mi := &file_khata_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Explanation.ProtoReflect.Descriptor instead.
+func (*Explanation) Descriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Explanation) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Explanation) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Explanation) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Explanation) GetFunctionName() string {
+	if x != nil {
+		return x.FunctionName
+	}
+	return ""
+}
+
+func (x *Explanation) GetFields() *structpb.Struct {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *Explanation) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *Explanation) GetGoroutine() uint64 {
+	if x != nil {
+		return x.Goroutine
+	}
+	return 0
+}
+
+type Frame struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	File         string                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	Line         int32                  `protobuf:"varint,2,opt,name=line,proto3" json:"line,omitempty"`
+	FunctionName string                 `protobuf:"bytes,3,opt,name=function_name,json=functionName,proto3" json:"function_name,omitempty"`
+	// Number of frames folded into this one by the trace filters.
+	Folded int32 `protobuf:"varint,4,opt,name=folded,proto3" json:"folded,omitempty"`
+	// Whether the frame belongs to the main module of the program.
+	OwnCode       bool `protobuf:"varint,5,opt,name=own_code,json=ownCode,proto3" json:"own_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_khata_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_khata_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Frame) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Frame) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Frame) GetFunctionName() string {
+	if x != nil {
+		return x.FunctionName
+	}
+	return ""
+}
+
+func (x *Frame) GetFolded() int32 {
+	if x != nil {
+		return x.Folded
+	}
+	return 0
+}
+
+func (x *Frame) GetOwnCode() bool {
+	if x != nil {
+		return x.OwnCode
+	}
+	return false
+}
+
+type Environment struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ID of the goroutine that captured the environment.
+	Goroutine uint64 `protobuf:"varint,1,opt,name=goroutine,proto3" json:"goroutine,omitempty"`
+	// pprof labels of the context given to CaptureEnvironment.
+	Labels   map[string]string `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Hostname string            `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Pid      int64             `protobuf:"varint,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	// Path and version of the main module, from the build info.
+	Module        string `protobuf:"bytes,5,opt,name=module,proto3" json:"module,omitempty"`
+	ModuleVersion string `protobuf:"bytes,6,opt,name=module_version,json=moduleVersion,proto3" json:"module_version,omitempty"`
+	GoVersion     string `protobuf:"bytes,7,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	VcsRevision   string `protobuf:"bytes,8,opt,name=vcs_revision,json=vcsRevision,proto3" json:"vcs_revision,omitempty"`
+	VcsTime       string `protobuf:"bytes,9,opt,name=vcs_time,json=vcsTime,proto3" json:"vcs_time,omitempty"`
+	VcsModified   bool   `protobuf:"varint,10,opt,name=vcs_modified,json=vcsModified,proto3" json:"vcs_modified,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Environment) Reset() {
+	*x = Environment{}
+	mi := &file_khata_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Environment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Environment) ProtoMessage() {}
+
+func (x *Environment) ProtoReflect() protoreflect.Message {
+	mi := &file_khata_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Environment.ProtoReflect.Descriptor instead.
+func (*Environment) Descriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Environment) GetGoroutine() uint64 {
+	if x != nil {
+		return x.Goroutine
+	}
+	return 0
+}
+
+func (x *Environment) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Environment) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Environment) GetPid() int64 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *Environment) GetModule() string {
+	if x != nil {
+		return x.Module
+	}
+	return ""
+}
+
+func (x *Environment) GetModuleVersion() string {
+	if x != nil {
+		return x.ModuleVersion
+	}
+	return ""
+}
+
+func (x *Environment) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *Environment) GetVcsRevision() string {
+	if x != nil {
+		return x.VcsRevision
+	}
+	return ""
+}
+
+func (x *Environment) GetVcsTime() string {
+	if x != nil {
+		return x.VcsTime
+	}
+	return ""
+}
+
+func (x *Environment) GetVcsModified() bool {
+	if x != nil {
+		return x.VcsModified
+	}
+	return false
+}
+
+// Goroutines sharing the same state and stack.
+type GoroutineGroup struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Trace         []*Frame               `protobuf:"bytes,3,rep,name=trace,proto3" json:"trace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GoroutineGroup) Reset() {
+	*x = GoroutineGroup{}
+	mi := &file_khata_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GoroutineGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GoroutineGroup) ProtoMessage() {}
+
+func (x *GoroutineGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_khata_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GoroutineGroup.ProtoReflect.Descriptor instead.
+func (*GoroutineGroup) Descriptor() ([]byte, []int) {
+	return file_khata_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GoroutineGroup) GetIds() []uint64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *GoroutineGroup) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *GoroutineGroup) GetTrace() []*Frame {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+var File_khata_proto protoreflect.FileDescriptor
+
+const file_khata_proto_rawDesc = "" +
+	"\n" +
+	"\vkhata.proto\x12\bkhata.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfb\x04\n" +
+	"\x05Error\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x03R\x04code\x12\x1b\n" +
+	"\texit_code\x18\x04 \x01(\x05R\bexitCode\x12.\n" +
+	"\bseverity\x18\x05 \x01(\x0e2\x12.khata.v1.SeverityR\bseverity\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"handled_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\thandledAt\x129\n" +
+	"\fexplanations\x18\b \x03(\v2\x15.khata.v1.ExplanationR\fexplanations\x12%\n" +
+	"\x05trace\x18\t \x03(\v2\x0f.khata.v1.FrameR\x05trace\x127\n" +
+	"\n" +
+	"properties\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\n" +
+	"properties\x12%\n" +
+	"\x05cause\x18\v \x01(\v2\x0f.khata.v1.ErrorR\x05cause\x12\x1b\n" +
+	"\tcode_name\x18\f \x01(\tR\bcodeName\x127\n" +
+	"\venvironment\x18\r \x01(\v2\x15.khata.v1.EnvironmentR\venvironment\x128\n" +
+	"\n" +
+	"goroutines\x18\x0e \x03(\v2\x18.khata.v1.GoroutineGroupR\n" +
+	"goroutines\x12\x1b\n" +
+	"\tnon_fatal\x18\x0f \x01(\bR\bnonFatal\"\xf8\x01\n" +
+	"\vExplanation\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x12\n" +
+	"\x04file\x18\x02 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x03 \x01(\x05R\x04line\x12#\n" +
+	"\rfunction_name\x18\x04 \x01(\tR\ffunctionName\x12/\n" +
+	"\x06fields\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x06fields\x123\n" +
+	"\aelapsed\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x12\x1c\n" +
+	"\tgoroutine\x18\a \x01(\x04R\tgoroutine\"\x87\x01\n" +
+	"\x05Frame\x12\x12\n" +
+	"\x04file\x18\x01 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\x05R\x04line\x12#\n" +
+	"\rfunction_name\x18\x03 \x01(\tR\ffunctionName\x12\x16\n" +
+	"\x06folded\x18\x04 \x01(\x05R\x06folded\x12\x19\n" +
+	"\bown_code\x18\x05 \x01(\bR\aownCode\"\x8e\x03\n" +
+	"\vEnvironment\x12\x1c\n" +
+	"\tgoroutine\x18\x01 \x01(\x04R\tgoroutine\x129\n" +
+	"\x06labels\x18\x02 \x03(\v2!.khata.v1.Environment.LabelsEntryR\x06labels\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\x03R\x03pid\x12\x16\n" +
+	"\x06module\x18\x05 \x01(\tR\x06module\x12%\n" +
+	"\x0emodule_version\x18\x06 \x01(\tR\rmoduleVersion\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\a \x01(\tR\tgoVersion\x12!\n" +
+	"\fvcs_revision\x18\b \x01(\tR\vvcsRevision\x12\x19\n" +
+	"\bvcs_time\x18\t \x01(\tR\avcsTime\x12!\n" +
+	"\fvcs_modified\x18\n" +
+	" \x01(\bR\vvcsModified\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"_\n" +
+	"\x0eGoroutineGroup\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x04R\x03ids\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12%\n" +
+	"\x05trace\x18\x03 \x03(\v2\x0f.khata.v1.FrameR\x05trace*\xa0\x01\n" +
+	"\bSeverity\x12\x18\n" +
+	"\x14SEVERITY_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0eSEVERITY_DEBUG\x10\x01\x12\x11\n" +
+	"\rSEVERITY_INFO\x10\x02\x12\x14\n" +
+	"\x10SEVERITY_WARNING\x10\x03\x12\x12\n" +
+	"\x0eSEVERITY_ERROR\x10\x04\x12\x15\n" +
+	"\x11SEVERITY_CRITICAL\x10\x05\x12\x12\n" +
+	"\x0eSEVERITY_FATAL\x10\x06B#Z!github.com/cmseguin/khata/khatapbb\x06proto3"
+
+var (
+	file_khata_proto_rawDescOnce sync.Once
+	file_khata_proto_rawDescData []byte
+)
+
+func file_khata_proto_rawDescGZIP() []byte {
+	file_khata_proto_rawDescOnce.Do(func() {
+		file_khata_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_khata_proto_rawDesc), len(file_khata_proto_rawDesc)))
+	})
+	return file_khata_proto_rawDescData
+}
+
+var file_khata_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_khata_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_khata_proto_goTypes = []any{
+	(Severity)(0),                 // 0: khata.v1.Severity
+	(*Error)(nil),                 // 1: khata.v1.Error
+	(*Explanation)(nil),           // 2: khata.v1.Explanation
+	(*Frame)(nil),                 // 3: khata.v1.Frame
+	(*Environment)(nil),           // 4: khata.v1.Environment
+	(*GoroutineGroup)(nil),        // 5: khata.v1.GoroutineGroup
+	nil,                           // 6: khata.v1.Environment.LabelsEntry
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 8: google.protobuf.Struct
+	(*durationpb.Duration)(nil),   // 9: google.protobuf.Duration
+}
+var file_khata_proto_depIdxs = []int32{
+	0,  // 0: khata.v1.Error.severity:type_name -> khata.v1.Severity
+	7,  // 1: khata.v1.Error.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 2: khata.v1.Error.handled_at:type_name -> google.protobuf.Timestamp
+	2,  // 3: khata.v1.Error.explanations:type_name -> khata.v1.Explanation
+	3,  // 4: khata.v1.Error.trace:type_name -> khata.v1.Frame
+	8,  // 5: khata.v1.Error.properties:type_name -> google.protobuf.Struct
+	1,  // 6: khata.v1.Error.cause:type_name -> khata.v1.Error
+	4,  // 7: khata.v1.Error.environment:type_name -> khata.v1.Environment
+	5,  // 8: khata.v1.Error.goroutines:type_name -> khata.v1.GoroutineGroup
+	8,  // 9: khata.v1.Explanation.fields:type_name -> google.protobuf.Struct
+	9,  // 10: khata.v1.Explanation.elapsed:type_name -> google.protobuf.Duration
+	6,  // 11: khata.v1.Environment.labels:type_name -> khata.v1.Environment.LabelsEntry
+	3,  // 12: khata.v1.GoroutineGroup.trace:type_name -> khata.v1.Frame
+	13, // [13:13] is the sub-list for method output_type
+	13, // [13:13] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_khata_proto_init() }
+func file_khata_proto_init() {
+	if File_khata_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_khata_proto_rawDesc), len(file_khata_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_khata_proto_goTypes,
+		DependencyIndexes: file_khata_proto_depIdxs,
+		EnumInfos:         file_khata_proto_enumTypes,
+		MessageInfos:      file_khata_proto_msgTypes,
+	}.Build()
+	File_khata_proto = out.File
+	file_khata_proto_goTypes = nil
+	file_khata_proto_depIdxs = nil
+}
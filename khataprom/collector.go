@@ -0,0 +1,102 @@
+// Package khataprom exposes metrics about khata errors to Prometheus.
+//
+//	labeler := khatametrics.NewLabeler().
+//		RegisterTemplate("not_found", NotFound).
+//		RegisterTemplate("internal", InternalError)
+//
+//	collector := khataprom.NewCollector(labeler)
+//	prometheus.MustRegister(collector)
+//	khata.AddObserver(collector)
+package khataprom
+
+import (
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatametrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// Option used to configure the collector
+type Option func(*options)
+
+// Prefix the metric names with the namespace
+func Namespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// Use the given buckets for the handling latency histogram
+func Buckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// Collector counts created and handled errors and observes the time between
+// their creation and their handling. It is both a prometheus.Collector and a
+// khata.Observer, labeled by type, code, template and severity.
+type Collector struct {
+	labeler *khatametrics.Labeler
+	created *prometheus.CounterVec
+	handled *prometheus.CounterVec
+	latency *prometheus.HistogramVec
+}
+
+// Create a collector labeling errors with the labeler
+func NewCollector(labeler *khatametrics.Labeler, opts ...Option) *Collector {
+	o := options{buckets: prometheus.DefBuckets}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Collector{
+		labeler: labeler,
+		created: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "khata",
+			Name:      "errors_created_total",
+			Help:      "Number of khata errors created.",
+		}, khatametrics.LabelNames),
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "khata",
+			Name:      "errors_handled_total",
+			Help:      "Number of khata errors handled.",
+		}, khatametrics.LabelNames),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "khata",
+			Name:      "error_handling_seconds",
+			Help:      "Time between the creation and the handling of khata errors.",
+			Buckets:   o.buckets,
+		}, khatametrics.LabelNames),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.created.Describe(ch)
+	c.handled.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.created.Collect(ch)
+	c.handled.Collect(ch)
+	c.latency.Collect(ch)
+}
+
+func (c *Collector) ErrorCreated(k *khata.Khata) {
+	c.created.WithLabelValues(c.labeler.Labels(k).Values()...).Inc()
+}
+
+func (c *Collector) ErrorHandled(k *khata.Khata) {
+	values := c.labeler.Labels(k).Values()
+	c.handled.WithLabelValues(values...).Inc()
+	c.latency.WithLabelValues(values...).Observe(k.HandledAt().Sub(k.CreatedAt()).Seconds())
+}
@@ -0,0 +1,51 @@
+package khataprom_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatametrics"
+	"github.com/cmseguin/khata/khataprom"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	current := time.Date(2023, 7, 2, 4, 27, 35, 0, time.UTC)
+	khata.SetClock(khata.ClockFunc(func() time.Time { return current }))
+	defer khata.SetClock(nil)
+
+	notFound := khata.NewTemplate().SetType("HTTP").SetCode(404)
+	collector := khataprom.NewCollector(
+		khatametrics.NewLabeler().RegisterTemplate("not_found", notFound),
+		khataprom.Buckets([]float64{1, 5}),
+	)
+	defer khata.AddObserver(collector)()
+
+	notFound.New()
+	k := notFound.New()
+	current = current.Add(2 * time.Second)
+	k.MarkHandled()
+
+	expected := `
+# HELP khata_errors_created_total Number of khata errors created.
+# TYPE khata_errors_created_total counter
+khata_errors_created_total{code="404",severity="fatal",template="not_found",type="HTTP"} 2
+# HELP khata_errors_handled_total Number of khata errors handled.
+# TYPE khata_errors_handled_total counter
+khata_errors_handled_total{code="404",severity="fatal",template="not_found",type="HTTP"} 1
+# HELP khata_error_handling_seconds Time between the creation and the handling of khata errors.
+# TYPE khata_error_handling_seconds histogram
+khata_error_handling_seconds_bucket{code="404",severity="fatal",template="not_found",type="HTTP",le="1"} 0
+khata_error_handling_seconds_bucket{code="404",severity="fatal",template="not_found",type="HTTP",le="5"} 1
+khata_error_handling_seconds_bucket{code="404",severity="fatal",template="not_found",type="HTTP",le="+Inf"} 1
+khata_error_handling_seconds_sum{code="404",severity="fatal",template="not_found",type="HTTP"} 2
+khata_error_handling_seconds_count{code="404",severity="fatal",template="not_found",type="HTTP"} 1
+`
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error("Collector did not expose the expected metrics: " + err.Error())
+		return
+	}
+}
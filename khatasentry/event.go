@@ -0,0 +1,252 @@
+// Package khatasentry reports khata errors to a server speaking the Sentry
+// envelope protocol, without depending on the Sentry SDK.
+//
+//	reporter, err := khatasentry.NewReporter(dsn, khatasentry.Tags("tenant", "region"))
+//	if err != nil {
+//		return err
+//	}
+//	eventID, err := reporter.Report(ctx, k)
+//
+// The exception type is the type of the error and its value the error message,
+// with one exception per layer of the wrap chain. Stack frames come from the
+// creation trace, explanations become breadcrumbs, the selected properties
+// become tags and the other properties extra data.
+package khatasentry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+// Event is the Sentry event built from a khata error
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       string                 `json:"level"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Exception   *Exceptions            `json:"exception,omitempty"`
+	Breadcrumbs *Breadcrumbs           `json:"breadcrumbs,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Fingerprint []string               `json:"fingerprint,omitempty"`
+}
+
+// Exceptions of an event, from the root cause to the outermost error
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace of an exception, from the oldest call to the most recent one
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+type Breadcrumbs struct {
+	Values []Breadcrumb `json:"values"`
+}
+
+type Breadcrumb struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Type      string                 `json:"type"`
+	Category  string                 `json:"category"`
+	Message   string                 `json:"message"`
+	Level     string                 `json:"level"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+var levels = map[khata.Severity]string{
+	khata.SeverityDebug:    "debug",
+	khata.SeverityInfo:     "info",
+	khata.SeverityWarning:  "warning",
+	khata.SeverityError:    "error",
+	khata.SeverityCritical: "fatal",
+	khata.SeverityFatal:    "fatal",
+}
+
+// Build the Sentry event of the error
+func NewEvent(k *khata.Khata, opts ...Option) *Event {
+	o := newOptions(opts)
+	return o.event(k)
+}
+
+func (o *options) event(k *khata.Khata) *Event {
+	event := &Event{
+		EventID:     newEventID(),
+		Timestamp:   k.CreatedAt(),
+		Platform:    "go",
+		Level:       levels[k.Severity()],
+		Release:     o.release,
+		Environment: o.environment,
+		Fingerprint: []string{k.Fingerprint()},
+	}
+
+	if env := k.Environment(); env != nil {
+		event.ServerName = env.Hostname
+	}
+
+	chain := k.Chain()
+	exceptions := make([]Exception, 0, len(chain))
+	for i := len(chain) - 1; i >= 0; i-- {
+		exceptions = append(exceptions, o.exception(chain[i]))
+	}
+	event.Exception = &Exceptions{Values: exceptions}
+
+	if explanations := k.Explanations(); len(explanations) != 0 {
+		breadcrumbs := make([]Breadcrumb, 0, len(explanations))
+		for _, explanation := range explanations {
+			breadcrumbs = append(breadcrumbs, breadcrumb(k.CreatedAt(), explanation))
+		}
+		event.Breadcrumbs = &Breadcrumbs{Values: breadcrumbs}
+	}
+
+	k.RangeSerializedProperties(func(key string, value interface{}) bool {
+		if o.tags[key] {
+			if event.Tags == nil {
+				event.Tags = map[string]string{}
+			}
+			event.Tags[key] = fmt.Sprint(value)
+			return true
+		}
+
+		if event.Extra == nil {
+			event.Extra = map[string]interface{}{}
+		}
+		event.Extra[key] = jsonValue(value)
+		return true
+	})
+
+	return event
+}
+
+func (o *options) exception(k *khata.Khata) Exception {
+	exception := Exception{Type: k.Type(), Value: k.Error()}
+
+	trace := k.CreationTrace()
+	if len(trace) == 0 {
+		return exception
+	}
+
+	// Sentry lists the frames from the oldest call to the most recent one
+	frames := make([]Frame, 0, len(trace))
+	for i := len(trace) - 1; i >= 0; i-- {
+		frames = append(frames, o.frame(trace[i]))
+	}
+	exception.Stacktrace = &Stacktrace{Frames: frames}
+
+	return exception
+}
+
+func (o *options) frame(kt khata.KhataTrace) Frame {
+	module, function := splitFunctionName(kt.FunctionName())
+
+	return Frame{
+		Function: function,
+		Module:   module,
+		Filename: o.trimPath(kt.File()),
+		AbsPath:  kt.File(),
+		Lineno:   kt.Line(),
+		InApp:    kt.IsOwnCode() || o.inApp(module),
+	}
+}
+
+// Check if the package is under one of the in app prefixes
+func (o *options) inApp(module string) bool {
+	for _, prefix := range o.inAppPrefixes {
+		if module == prefix || strings.HasPrefix(module, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Trim the longest matching path prefix of the khata configuration
+func (o *options) trimPath(file string) string {
+	longest := ""
+	for _, prefix := range o.pathTrimPrefixes {
+		if len(prefix) > len(longest) && strings.HasPrefix(file, prefix) {
+			longest = prefix
+		}
+	}
+	return strings.TrimPrefix(file, longest)
+}
+
+func breadcrumb(createdAt time.Time, explanation khata.KhataExplanation) Breadcrumb {
+	data := map[string]interface{}{
+		"location": fmt.Sprintf("%s:%d", explanation.File, explanation.Line),
+	}
+
+	keys := make([]string, 0, len(explanation.Fields))
+	for key := range explanation.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		data[key] = jsonValue(explanation.Fields[key])
+	}
+
+	return Breadcrumb{
+		Timestamp: createdAt.Add(explanation.Elapsed),
+		Type:      "default",
+		Category:  "explanation",
+		Message:   explanation.Message,
+		Level:     "info",
+		Data:      data,
+	}
+}
+
+// Returns the value, or the value formatted with %v if it cannot be marshaled,
+// like ToJSON does, so a single invalid value does not drop the whole event
+func jsonValue(value interface{}) interface{} {
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return value
+}
+
+// Split a function name like github.com/org/pkg.(*Type).Method into its package and function
+func splitFunctionName(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// Returns a random event ID: 32 lowercase hex digits
+func newEventID() string {
+	var id [16]byte
+	rand.Read(id[:])
+
+	// Version 4 UUID
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,218 @@
+package khatasentry_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatasentry"
+)
+
+var NotFound = khata.NewTemplate().SetType("NotFound").SetCode(404).SetNonFatal()
+
+func createError() *khata.Khata {
+	cause := khata.New("row missing")
+	return NotFound.Wrap(cause).
+		SetProperty("tenant", "acme").
+		SetProperty("query", "SELECT 1").
+		Explain("Looked up the user")
+}
+
+func TestNewEvent(t *testing.T) {
+	k := createError()
+	event := khatasentry.NewEvent(k, khatasentry.Tags("tenant"), khatasentry.Release("1.2.3"))
+
+	if event.Level != "error" || event.Release != "1.2.3" || event.Platform != "go" || len(event.EventID) != 32 {
+		t.Error("NewEvent() did not set the level, release, platform and event ID")
+		return
+	}
+
+	if len(event.Fingerprint) != 1 || event.Fingerprint[0] != k.Fingerprint() {
+		t.Error("NewEvent() did not use the fingerprint of the error")
+		return
+	}
+
+	exceptions := event.Exception.Values
+	if len(exceptions) != 2 || exceptions[0].Type != khata.DEFAULT_ERROR_TYPE || exceptions[1].Type != "NotFound" || exceptions[1].Value != k.Error() {
+		t.Error("NewEvent() did not list the exceptions from the root cause to the error")
+		return
+	}
+
+	frames := exceptions[1].Stacktrace.Frames
+	last := frames[len(frames)-1]
+	if last.Module != "github.com/cmseguin/khata/khatasentry_test" || last.Function != "createError" || !last.InApp {
+		t.Error("NewEvent() did not end the stacktrace with the in app frame creating the error")
+		return
+	}
+
+	if frames[0].InApp {
+		t.Error("NewEvent() marked a runtime frame as in app")
+		return
+	}
+
+	if event.Tags["tenant"] != "acme" || event.Extra["query"] != "SELECT 1" || event.Extra["tenant"] != nil {
+		t.Error("NewEvent() did not split the properties into tags and extra data")
+		return
+	}
+
+	breadcrumbs := event.Breadcrumbs.Values
+	if len(breadcrumbs) != 1 || breadcrumbs[0].Message != "Looked up the user" || breadcrumbs[0].Category != "explanation" {
+		t.Error("NewEvent() did not turn the explanations into breadcrumbs")
+		return
+	}
+}
+
+func TestNewEventInApp(t *testing.T) {
+	event := khatasentry.NewEvent(createError(), khatasentry.InApp("testing"))
+
+	frames := event.Exception.Values[1].Stacktrace.Frames
+	for _, frame := range frames {
+		if frame.Module == "testing" && !frame.InApp {
+			t.Error("NewEvent() did not mark the frames of the in app packages")
+			return
+		}
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := khatasentry.ParseDSN("https://public@sentry.example.com/errors/42")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dsn.EnvelopeURL() != "https://sentry.example.com/errors/api/42/envelope/" {
+		t.Error("EnvelopeURL() did not keep the path of the DSN")
+		return
+	}
+
+	for _, invalid := range []string{"ftp://public@host/42", "https://host/42", "https://public@host/"} {
+		if _, err := khatasentry.ParseDSN(invalid); err == nil {
+			t.Error("ParseDSN() accepted " + invalid)
+			return
+		}
+	}
+}
+
+func TestReporterReport(t *testing.T) {
+	var path, auth string
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("X-Sentry-Auth")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dsn := strings.Replace(server.URL, "http://", "http://public@", 1) + "/42"
+
+	reporter, err := khatasentry.NewReporter(dsn, khatasentry.WithClock(khata.ClockFunc(func() time.Time { return sentAt })))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventID, err := reporter.Report(context.Background(), createError())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if path != "/api/42/envelope/" || !strings.Contains(auth, "sentry_key=public") {
+		t.Error("Report() did not post to the envelope endpoint with the public key")
+		return
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+	if len(lines) != 3 {
+		t.Error("Report() did not send a header, an item header and an event")
+		return
+	}
+
+	var header struct {
+		EventID string `json:"event_id"`
+		SentAt  string `json:"sent_at"`
+	}
+	var item struct {
+		Type   string `json:"type"`
+		Length int    `json:"length"`
+	}
+	var event khatasentry.Event
+
+	if json.Unmarshal(lines[0], &header) != nil || json.Unmarshal(lines[1], &item) != nil || json.Unmarshal(lines[2], &event) != nil {
+		t.Error("Report() sent an envelope that is not JSON lines")
+		return
+	}
+
+	if header.EventID != eventID || header.SentAt != "2024-01-02T03:04:05Z" || event.EventID != eventID {
+		t.Error("Report() did not send the event ID and the time of sending")
+		return
+	}
+
+	if item.Type != "event" || item.Length != len(lines[2]) {
+		t.Error("Report() did not send the type and the length of the item")
+		return
+	}
+}
+
+func TestReporterReportStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	reporter, err := khatasentry.NewReporter(strings.Replace(server.URL, "http://", "http://public@", 1) + "/42")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := reporter.Report(context.Background(), createError()); err == nil {
+		t.Error("Report() did not fail when the server rejected the event")
+		return
+	}
+}
+
+func TestReporterReportInvalidValues(t *testing.T) {
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	reporter, err := khatasentry.NewReporter(strings.Replace(server.URL, "http://", "http://public@", 1) + "/42")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	k := createError().SetProperty("done", make(chan struct{})).ExplainWith("Retried", "callback", func() {})
+
+	if _, err := reporter.Report(context.Background(), k); err != nil {
+		t.Error("Report() dropped an event with values that cannot be marshaled: " + err.Error())
+		return
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+
+	var event khatasentry.Event
+	if len(lines) != 3 || json.Unmarshal(lines[2], &event) != nil {
+		t.Error("Report() did not send the event")
+		return
+	}
+
+	if done, ok := event.Extra["done"].(string); !ok || !strings.HasPrefix(done, "0x") || event.Extra["query"] != "SELECT 1" {
+		t.Error("Report() did not format the values that cannot be marshaled")
+		return
+	}
+}
@@ -0,0 +1,237 @@
+package khatasentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cmseguin/khata"
+)
+
+// Name of the client sent in the authentication header
+const CLIENT_NAME = "khatasentry/1.0"
+
+type options struct {
+	tags             map[string]bool
+	inAppPrefixes    []string
+	pathTrimPrefixes []string
+	release          string
+	environment      string
+	client           *http.Client
+	clock            khata.Clock
+}
+
+// Option used to configure the events and the reporter
+type Option func(*options)
+
+// Send the given properties as tags instead of extra data
+func Tags(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.tags[key] = true
+		}
+	}
+}
+
+// Mark the frames of the given packages, and the packages under them, as in app.
+// Frames of the main module are always in app.
+func InApp(prefixes ...string) Option {
+	return func(o *options) {
+		o.inAppPrefixes = append(o.inAppPrefixes, prefixes...)
+	}
+}
+
+// Set the release of the events
+func Release(release string) Option {
+	return func(o *options) {
+		o.release = release
+	}
+}
+
+// Set the environment of the events, like production or staging
+func Environment(environment string) Option {
+	return func(o *options) {
+		o.environment = environment
+	}
+}
+
+// Send the envelopes with the given client instead of http.DefaultClient
+func HTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// Use the given clock for the time the envelopes are sent
+func WithClock(clock khata.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		tags:             map[string]bool{},
+		pathTrimPrefixes: khata.CurrentConfig().PathTrimPrefixes,
+		client:           http.DefaultClient,
+		clock:            khata.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// DSN identifies the project events are sent to, like https://key@host/42
+type DSN struct {
+	scheme    string
+	host      string
+	path      string
+	publicKey string
+	projectID string
+}
+
+// Parse a DSN of the form scheme://public_key@host[:port][/path]/project_id
+func ParseDSN(dsn string) (*DSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid DSN: unsupported scheme %q", u.Scheme)
+	}
+
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("invalid DSN: missing public key")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, errors.New("invalid DSN: missing project ID")
+	}
+
+	return &DSN{
+		scheme:    u.Scheme,
+		host:      u.Host,
+		path:      path[:slash],
+		publicKey: u.User.Username(),
+		projectID: projectID,
+	}, nil
+}
+
+// Returns the URL envelopes are posted to
+func (d *DSN) EnvelopeURL() string {
+	return fmt.Sprintf("%s://%s%s/api/%s/envelope/", d.scheme, d.host, d.path, d.projectID)
+}
+
+// Returns the DSN without its secret parts
+func (d *DSN) String() string {
+	return fmt.Sprintf("%s://%s@%s%s/%s", d.scheme, d.publicKey, d.host, d.path, d.projectID)
+}
+
+// Reporter sends khata errors as Sentry events
+type Reporter struct {
+	dsn     *DSN
+	options *options
+}
+
+// Create a reporter sending the events to the project of the DSN
+func NewReporter(dsn string, opts ...Option) (*Reporter, error) {
+	parsed, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reporter{dsn: parsed, options: newOptions(opts)}, nil
+}
+
+// Build the Sentry event of the error with the options of the reporter
+func (r *Reporter) Event(k *khata.Khata) *Event {
+	return r.options.event(k)
+}
+
+// Send the error as a Sentry event. Returns the ID of the event.
+func (r *Reporter) Report(ctx context.Context, k *khata.Khata) (string, error) {
+	event := r.Event(k)
+	return event.EventID, r.Send(ctx, event)
+}
+
+// Send the event in an envelope
+func (r *Reporter) Send(ctx context.Context, event *Event) error {
+	body, err := r.envelope(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.dsn.EnvelopeURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+		"Sentry sentry_version=7, sentry_client=%s, sentry_key=%s",
+		CLIENT_NAME, r.dsn.publicKey,
+	))
+
+	res, err := r.options.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("sending event %s: unexpected status %s", event.EventID, res.Status)
+	}
+
+	return nil
+}
+
+// Encode the event as an envelope: the envelope header, the item header and
+// the event, each on its own line
+func (r *Reporter) envelope(event *Event) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  r.options.clock.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      r.dsn.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := json.Marshal(map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(item)
+	buf.WriteByte('\n')
+	buf.Write(payload)
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package khatatest
+
+import (
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// Snapshot holds the parts of a khata error that are stable across runs.
+// Timestamps, traces, file paths and line numbers are left out.
+type Snapshot struct {
+	Error        string
+	Type         string
+	Code         int
+	CodeName     string
+	ExitCode     int
+	NonFatal     bool
+	Severity     khata.Severity
+	Properties   map[string]interface{}
+	Explanations []ExplanationSnapshot
+	Cause        *Snapshot
+}
+
+// ExplanationSnapshot holds the stable parts of an explanation
+type ExplanationSnapshot struct {
+	Message string
+	Fields  map[string]interface{}
+}
+
+// Returns the snapshot of the error and its causes
+func Snap(k *khata.Khata) *Snapshot {
+	if k == nil {
+		return nil
+	}
+
+	snapshot := &Snapshot{
+		Error:      k.Error(),
+		Type:       k.Type(),
+		Code:       k.Code(),
+		CodeName:   k.CodeName(),
+		ExitCode:   k.ExitCode(),
+		NonFatal:   k.IsNonFatal(),
+		Severity:   k.Severity(),
+		Properties: map[string]interface{}{},
+		Cause:      Snap(k.Cause()),
+	}
+
+	for _, key := range k.PropertiesKeys() {
+		snapshot.Properties[key] = k.GetProperty(key)
+	}
+
+	for _, explanation := range k.Explanations() {
+		snapshot.Explanations = append(snapshot.Explanations, ExplanationSnapshot{
+			Message: explanation.Message,
+			Fields:  explanation.Fields,
+		})
+	}
+
+	return snapshot
+}
+
+// Returns the go-cmp options comparing khata errors by their snapshot, ignoring
+// volatile fields like timestamps and traces.
+//
+//	if diff := cmp.Diff(want, got, khatatest.CmpOptions()); diff != "" {
+//		t.Errorf("unexpected error (-want +got):\n%s", diff)
+//	}
+func CmpOptions() cmp.Option {
+	return cmp.Options{
+		cmp.Transformer("khata.Snap", Snap),
+		cmpopts.EquateEmpty(),
+	}
+}
+
+// Assert that the error matches the expected snapshot
+func AssertSnapshot(t testing.TB, err error, want *Snapshot) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if diff := cmp.Diff(want, Snap(k), cmpopts.EquateEmpty()); diff != "" {
+		fail(t, k, "unexpected error (-want +got):\n%s", diff)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package khatatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Golden files are rewritten instead of compared when this environment variable is set
+const UpdateEnv = "KHATA_UPDATE_GOLDEN"
+
+var (
+	ansiPattern      = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	dateTimePattern  = regexp.MustCompile(`\d{4}[/-]\d{2}[/-]\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?( \d+\.\d+ms)?(Z|[+-]\d{2}:?\d{2})*`)
+	durationPattern  = regexp.MustCompile(`\d+\.\d+m?s\b`)
+	goroutinePattern = regexp.MustCompile(`(?m)(\) |^\s+Goroutine\s+-> )g\d+$`)
+	goroutineList    = regexp.MustCompile(`(?m)^(\s+\d+ goroutines? \[[^\]]*\]: )g\d+(?:, g\d+)*$`)
+	locationPattern  = regexp.MustCompile(`(?:[^\s():]*/)?([^\s/():]+\.(?:go|s)):\d+`)
+	asmFilePattern   = regexp.MustCompile(`\basm_\w+\.s\b`)
+)
+
+// Removes colors and replaces timestamps, durations, goroutine IDs, directories,
+// line numbers and architecture specific files from Debug() output so it can be
+// compared across runs and machines. Goroutine IDs are only replaced where Debug()
+// prints them, so messages and properties are compared as they are.
+func Normalize(output string) string {
+	output = ansiPattern.ReplaceAllString(output, "")
+	output = dateTimePattern.ReplaceAllString(output, "<time>")
+	output = durationPattern.ReplaceAllString(output, "<duration>")
+	output = goroutinePattern.ReplaceAllString(output, "${1}g<id>")
+	output = goroutineList.ReplaceAllString(output, "${1}g<id>")
+	output = locationPattern.ReplaceAllString(output, "$1:<line>")
+	output = asmFilePattern.ReplaceAllString(output, "asm.s")
+	return output
+}
+
+// Replaces the volatile fields of ToJSON() output and indents it so it can be
+// compared across runs and machines: the timestamps of the errors, the lines of
+// the trace frames, and the lines, goroutines and elapsed times of the explanations.
+// File paths are reduced to their base name. Properties and explanation fields
+// are compared as they are.
+func NormalizeJSON(output string) (string, error) {
+	var decoded interface{}
+
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(normalizeJSONValue(decoded)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func normalizeJSONValue(value interface{}) interface{} {
+	e, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	for _, key := range []string{"createdAt", "handledAt"} {
+		if _, ok := e[key]; ok {
+			e[key] = "<time>"
+		}
+	}
+
+	for _, explanation := range jsonObjects(e["explanations"]) {
+		normalizeJSONFrame(explanation)
+		explanation["elapsedMs"] = 0
+		explanation["goroutine"] = 0
+	}
+
+	for _, frame := range jsonObjects(e["trace"]) {
+		normalizeJSONFrame(frame)
+	}
+
+	for _, group := range jsonObjects(e["goroutines"]) {
+		for _, frame := range jsonObjects(group["trace"]) {
+			normalizeJSONFrame(frame)
+		}
+	}
+
+	if env, ok := e["environment"].(map[string]interface{}); ok {
+		env["goroutine"] = 0
+	}
+
+	if cause, ok := e["cause"]; ok {
+		e["cause"] = normalizeJSONValue(cause)
+	}
+
+	return e
+}
+
+func normalizeJSONFrame(frame map[string]interface{}) {
+	frame["line"] = 0
+	if path, ok := frame["file"].(string); ok {
+		frame["file"] = asmFilePattern.ReplaceAllString(filepath.Base(path), "asm.s")
+	}
+}
+
+// Returns the objects of a JSON array, skipping the other values
+func jsonObjects(value interface{}) []map[string]interface{} {
+	items, _ := value.([]interface{})
+
+	var objects []map[string]interface{}
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// Compare the normalized Debug() output of the error with the golden file
+func AssertGoldenDebug(t testing.TB, k *khata.Khata, path string) bool {
+	t.Helper()
+
+	var buf bytes.Buffer
+	k.DebugTo(&buf)
+
+	return assertGolden(t, k, path, Normalize(buf.String()))
+}
+
+// Compare the normalized ToJSON() output of the error with the golden file
+func AssertGoldenJSON(t testing.TB, k *khata.Khata, path string) bool {
+	t.Helper()
+
+	normalized, err := NormalizeJSON(k.ToJSON())
+	if err != nil {
+		fail(t, k, "invalid JSON output: %s", err)
+		return false
+	}
+
+	return assertGolden(t, k, path, normalized)
+}
+
+func assertGolden(t testing.TB, k *khata.Khata, path string, got string) bool {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("could not create the golden file directory: %s", err)
+			return false
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Errorf("could not update the golden file: %s", err)
+			return false
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("could not read the golden file, run the tests with %s=1 to create it: %s", UpdateEnv, err)
+		return false
+	}
+
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		fail(t, k, "output does not match %s (-want +got):\n%s", path, diff)
+		return false
+	}
+
+	return true
+}
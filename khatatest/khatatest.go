@@ -0,0 +1,217 @@
+// Package khatatest provides helpers to verify khata errors in unit tests.
+//
+// Every assertion reports failures with t.Errorf and prints the rendered error,
+// so tests can keep running and the failure shows the full context.
+//
+//	func TestFindUser(t *testing.T) {
+//		_, err := FindUser(12)
+//
+//		khatatest.AssertTemplate(t, err, NotFound)
+//		khatatest.AssertProperty(t, err, "userID", 12)
+//		khatatest.AssertExplained(t, err, "not in the cache")
+//	}
+package khatatest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Returns the outermost khata error of err, reporting a failure if there is none
+func Require(t testing.TB, err error) *khata.Khata {
+	t.Helper()
+
+	var k *khata.Khata
+	if !errors.As(err, &k) {
+		t.Errorf("expected a *khata.Khata error, got %T: %v", err, err)
+		return nil
+	}
+
+	return k
+}
+
+// Assert that the error was created from the template or one of its children
+func AssertTemplate(t testing.TB, err error, template *khata.KhataTemplate) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.IsRelatedTo(template) {
+		fail(t, k, "expected the error to be created from the template %s", describeTemplate(template))
+		return false
+	}
+
+	return true
+}
+
+// Assert that one of the layers of the wrap chain was created from the template or one of its children
+func AssertChainContains(t testing.TB, err error, template *khata.KhataTemplate) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	for _, layer := range k.Chain() {
+		if layer.IsRelatedTo(template) {
+			return true
+		}
+	}
+
+	fail(t, k, "expected the wrap chain to contain an error created from the template %s", describeTemplate(template))
+	return false
+}
+
+// Assert the code of the error
+func AssertCode(t testing.TB, err error, code int) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.IsCode(code) {
+		fail(t, k, "expected code %d, got %d", code, k.Code())
+		return false
+	}
+
+	return true
+}
+
+// Assert the code of the error matches the pattern, like AUTH.* or 404
+func AssertCodeMatches(t testing.TB, err error, pattern string) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.MatchCode(pattern) {
+		fail(t, k, "expected code matching %q, got %s", pattern, k.ErrorCode())
+		return false
+	}
+
+	return true
+}
+
+// Assert the type of the error
+func AssertType(t testing.TB, err error, errorType string) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.IsType(errorType) {
+		fail(t, k, "expected type %q, got %q", errorType, k.Type())
+		return false
+	}
+
+	return true
+}
+
+// Assert the exit code of the error
+func AssertExitCode(t testing.TB, err error, code int) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.IsExitCode(code) {
+		fail(t, k, "expected exit code %d, got %d", code, k.ExitCode())
+		return false
+	}
+
+	return true
+}
+
+// Assert the severity of the error
+func AssertSeverity(t testing.TB, err error, severity khata.Severity) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if k.Severity() != severity {
+		fail(t, k, "expected severity %s, got %s", severity, k.Severity())
+		return false
+	}
+
+	return true
+}
+
+// Assert that the error has the property with the given value. Values are compared with go-cmp.
+func AssertProperty(t testing.TB, err error, key string, value interface{}) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	if !k.HasProperty(key) {
+		fail(t, k, "expected the property %q to be set", key)
+		return false
+	}
+
+	if diff := cmp.Diff(value, k.GetProperty(key)); diff != "" {
+		fail(t, k, "unexpected value for the property %q (-want +got):\n%s", key, diff)
+		return false
+	}
+
+	return true
+}
+
+// Assert that one of the explanations of the error, or of its causes, contains the substring
+func AssertExplained(t testing.TB, err error, substring string) bool {
+	t.Helper()
+
+	k := Require(t, err)
+	if k == nil {
+		return false
+	}
+
+	for _, layer := range k.Chain() {
+		for _, explanation := range layer.Explanations() {
+			if strings.Contains(explanation.Message, substring) {
+				return true
+			}
+		}
+	}
+
+	fail(t, k, "expected an explanation containing %q", substring)
+	return false
+}
+
+func fail(t testing.TB, k *khata.Khata, format string, args ...interface{}) {
+	t.Helper()
+	t.Errorf("%s\n%s", fmt.Sprintf(format, args...), render(k))
+}
+
+// Renders the error without colors for failure messages
+func render(k *khata.Khata) string {
+	var buf bytes.Buffer
+	k.DebugTo(&buf)
+	return ansiPattern.ReplaceAllString(buf.String(), "")
+}
+
+func describeTemplate(template *khata.KhataTemplate) string {
+	return fmt.Sprintf("(type %q, code %d)", template.Type(), template.Code())
+}
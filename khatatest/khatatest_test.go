@@ -0,0 +1,134 @@
+package khatatest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Records the failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+var HttpError = khata.NewTemplate().SetType("HTTP")
+var NotFound = HttpError.Extend().SetCode(404)
+var Database = khata.NewTemplate().SetType("Database")
+
+func newError() error {
+	inner := Database.New("connection refused").Explain("This is an explanation of the database")
+
+	return NotFound.Wrap(inner).
+		SetProperty("userID", 12).
+		Explain("This is an explanation of not found")
+}
+
+func TestAssertions(t *testing.T) {
+	err := newError()
+
+	khatatest.AssertTemplate(t, err, NotFound)
+	khatatest.AssertTemplate(t, err, HttpError)
+	khatatest.AssertChainContains(t, err, Database)
+	khatatest.AssertCode(t, err, 404)
+	khatatest.AssertType(t, err, "HTTP")
+	khatatest.AssertExitCode(t, err, 1)
+	khatatest.AssertSeverity(t, err, khata.SeverityFatal)
+	khatatest.AssertProperty(t, err, "userID", 12)
+	khatatest.AssertExplained(t, err, "of the database")
+}
+
+func TestAssertionFailures(t *testing.T) {
+	err := newError()
+	r := &recorder{TB: t}
+
+	khatatest.AssertTemplate(r, err, Database)
+	khatatest.AssertCode(r, err, 500)
+	khatatest.AssertProperty(r, err, "userID", 13)
+	khatatest.AssertExplained(r, err, "missing")
+	khatatest.AssertChainContains(r, fmt.Errorf("plain"), Database)
+
+	if len(r.failures) != 5 {
+		t.Errorf("expected 5 failures, got %d", len(r.failures))
+		return
+	}
+
+	if !strings.Contains(r.failures[1], "expected code 500, got 404") || !strings.Contains(r.failures[1], "=== Details") {
+		t.Errorf("the failure did not describe and render the error: %s", r.failures[1])
+		return
+	}
+}
+
+func TestCmpOptions(t *testing.T) {
+	want := newError()
+	got := newError()
+
+	if diff := cmp.Diff(want, got, khatatest.CmpOptions()); diff != "" {
+		t.Errorf("errors built the same way are different (-want +got):\n%s", diff)
+		return
+	}
+
+	khatatest.AssertSnapshot(t, got, &khatatest.Snapshot{
+		Error:        "connection refused",
+		Type:         "HTTP",
+		Code:         404,
+		ExitCode:     1,
+		Severity:     khata.SeverityFatal,
+		Properties:   map[string]interface{}{"userID": 12},
+		Explanations: []khatatest.ExplanationSnapshot{{Message: "This is an explanation of not found"}},
+		Cause: &khatatest.Snapshot{
+			Error:        "connection refused",
+			Type:         "Database",
+			Code:         -1,
+			ExitCode:     1,
+			Severity:     khata.SeverityFatal,
+			Explanations: []khatatest.ExplanationSnapshot{{Message: "This is an explanation of the database"}},
+		},
+	})
+}
+
+func TestGolden(t *testing.T) {
+	k := newError().(*khata.Khata)
+
+	khatatest.AssertGoldenDebug(t, k, "testdata/debug.golden")
+	khatatest.AssertGoldenJSON(t, k, "testdata/json.golden")
+}
+
+func TestNormalizeKeepsUserValues(t *testing.T) {
+	k := khata.New("worker g1 failed").
+		SetProperty("line", 7).
+		SetProperty("goroutine", "g2").
+		ExplainWith("This is an explanation", "elapsedMs", 12)
+
+	var buf strings.Builder
+	k.DebugTo(&buf)
+
+	debug := khatatest.Normalize(buf.String())
+	if !strings.Contains(debug, "worker g1 failed") || !strings.Contains(debug, ") g<id>") {
+		t.Error("Normalize() did not only replace the goroutine IDs printed by Debug()")
+		return
+	}
+
+	normalized, err := khatatest.NormalizeJSON(k.ToJSON())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, expected := range []string{`"line": 7`, `"goroutine": "g2"`, `"elapsedMs": 12`, `"line": 0`, `"goroutine": 0`} {
+		if !strings.Contains(normalized, expected) {
+			t.Error("NormalizeJSON() did not keep the user values apart from the volatile fields, missing " + expected)
+			return
+		}
+	}
+}
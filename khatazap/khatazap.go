@@ -0,0 +1,273 @@
+// Package khatazap logs khata errors with zap. Errors are encoded field by
+// field with the zap encoder, without going through their JSON representation.
+//
+//	logger.Error("request failed", khatazap.Error(k))
+//
+// The core returned by NewCore also encodes the khata errors logged with zap.Error
+// or zap.NamedError, so existing logging calls get the context of the errors,
+// and logs them at the level of their severity.
+package khatazap
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var levels = map[khata.Severity]zapcore.Level{
+	khata.SeverityDebug:    zapcore.DebugLevel,
+	khata.SeverityInfo:     zapcore.InfoLevel,
+	khata.SeverityWarning:  zapcore.WarnLevel,
+	khata.SeverityError:    zapcore.ErrorLevel,
+	khata.SeverityCritical: zapcore.ErrorLevel,
+	khata.SeverityFatal:    zapcore.ErrorLevel,
+}
+
+// Returns the zap level of the severity of the error. Critical and fatal errors
+// are logged at the error level, exiting is left to HandleKhata.
+func Level(k *khata.Khata) zapcore.Level {
+	return levels[k.Severity()]
+}
+
+// Returns a field logging the error under the "error" key
+func Error(k *khata.Khata) zap.Field {
+	return NamedError("error", k)
+}
+
+// Returns a field logging the error under the given key
+func NamedError(key string, k *khata.Khata) zap.Field {
+	return zap.Object(key, Marshaler(k))
+}
+
+// Returns the error as a zap object: message, type, code, exit code, severity,
+// properties, explanations, trace and cause
+func Marshaler(k *khata.Khata) zapcore.ObjectMarshaler {
+	return khataObject{k}
+}
+
+type khataObject struct {
+	k *khata.Khata
+}
+
+func (o khataObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	k := o.k
+
+	enc.AddString("message", k.Err.Error())
+	enc.AddString("type", k.Type())
+	enc.AddInt("code", k.Code())
+	if k.CodeName() != "" {
+		enc.AddString("codeName", k.CodeName())
+	}
+	enc.AddInt("exitCode", k.ExitCode())
+	if k.IsNonFatal() {
+		enc.AddBool("nonFatal", true)
+	}
+	enc.AddString("severity", k.Severity().String())
+	enc.AddString("fingerprint", k.Fingerprint())
+
+	if err := enc.AddObject("properties", properties{k}); err != nil {
+		return err
+	}
+
+	if len(k.Explanations()) != 0 {
+		if err := enc.AddArray("explanations", explanations(k.Explanations())); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.AddArray("trace", trace(k.CreationTrace())); err != nil {
+		return err
+	}
+
+	if cause := k.Cause(); cause != nil {
+		return enc.AddObject("cause", khataObject{cause})
+	}
+
+	return nil
+}
+
+type properties struct {
+	k *khata.Khata
+}
+
+func (p properties) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var err error
+
+	p.k.RangeSerializedProperties(func(key string, value interface{}) bool {
+		err = addValue(enc, key, value)
+		return err == nil
+	})
+
+	return err
+}
+
+type explanations []khata.KhataExplanation
+
+func (e explanations) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, explanation := range e {
+		if err := enc.AppendObject(explanationObject(explanation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type explanationObject khata.KhataExplanation
+
+func (e explanationObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.Message)
+	enc.AddString("file", e.File)
+	enc.AddInt("line", e.Line)
+	enc.AddString("functionName", e.FunctionName)
+	enc.AddDuration("elapsed", e.Elapsed)
+	enc.AddUint64("goroutine", e.Goroutine)
+
+	if len(e.Fields) != 0 {
+		return enc.AddObject("fields", fields(e.Fields))
+	}
+
+	return nil
+}
+
+type fields map[string]interface{}
+
+func (f fields) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := addValue(enc, key, f[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type trace []khata.KhataTrace
+
+func (t trace) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for i := range t {
+		if err := enc.AppendObject(frame{&t[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type frame struct {
+	t *khata.KhataTrace
+}
+
+func (f frame) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", f.t.File())
+	enc.AddInt("line", f.t.Line())
+	enc.AddString("functionName", f.t.FunctionName())
+	return nil
+}
+
+// Adds the value with the typed encoder methods when possible, to avoid reflection
+func addValue(enc zapcore.ObjectEncoder, key string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		enc.AddString(key, v)
+	case int:
+		enc.AddInt(key, v)
+	case int64:
+		enc.AddInt64(key, v)
+	case int32:
+		enc.AddInt32(key, v)
+	case uint:
+		enc.AddUint(key, v)
+	case uint64:
+		enc.AddUint64(key, v)
+	case float64:
+		enc.AddFloat64(key, v)
+	case bool:
+		enc.AddBool(key, v)
+	case time.Duration:
+		enc.AddDuration(key, v)
+	case time.Time:
+		enc.AddTime(key, v)
+	case *khata.Khata:
+		return enc.AddObject(key, khataObject{v})
+	case zapcore.ObjectMarshaler:
+		return enc.AddObject(key, v)
+	case error:
+		enc.AddString(key, v.Error())
+	default:
+		return enc.AddReflected(key, v)
+	}
+	return nil
+}
+
+// Returns a core encoding the khata errors found in the error fields, like the
+// ones created with zap.Error, before passing them to the given core. Other
+// errors are left untouched. Entries keep the level of the logging call, use
+// Level to log an error at the level of its severity.
+func NewCore(core zapcore.Core) zapcore.Core {
+	return khataCore{core}
+}
+
+type khataCore struct {
+	zapcore.Core
+}
+
+func (c khataCore) With(fields []zapcore.Field) zapcore.Core {
+	return khataCore{c.Core.With(replaceErrors(fields))}
+}
+
+func (c khataCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c khataCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, replaceErrors(fields))
+}
+
+// Returns the khata error of an error field, or nil
+func fieldError(field zapcore.Field) *khata.Khata {
+	if field.Type != zapcore.ErrorType {
+		return nil
+	}
+
+	err, ok := field.Interface.(error)
+	if !ok {
+		return nil
+	}
+
+	var k *khata.Khata
+	if !errors.As(err, &k) {
+		return nil
+	}
+	return k
+}
+
+// Returns the fields with the khata errors encoded as objects. The slice is
+// only copied when a khata error is found.
+func replaceErrors(fields []zapcore.Field) []zapcore.Field {
+	replaced := fields
+
+	for i, field := range fields {
+		k := fieldError(field)
+		if k == nil {
+			continue
+		}
+
+		if &replaced[0] == &fields[0] {
+			replaced = append([]zapcore.Field(nil), fields...)
+		}
+		replaced[i] = NamedError(field.Key, k)
+	}
+
+	return replaced
+}
@@ -0,0 +1,118 @@
+package khatazap_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatazap"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var userIDKey = khata.NewKey[int]("userID", khata.JSONName("user_id"))
+var passwordKey = khata.NewKey[string]("password", khata.Redacted())
+
+func newLogger(buf *bytes.Buffer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.DebugLevel)
+	return zap.New(khatazap.NewCore(core))
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal("The logger did not write JSON: " + err.Error())
+	}
+	return entry
+}
+
+func TestError(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	k := khata.New("This is an error message").SetType("HTTP").SetCode(404)
+	userIDKey.Set(k, 12)
+	passwordKey.Set(k, "secret")
+	k.ExplainWith("This is an explanation", "attempt", 2)
+
+	newLogger(buf).Error("request failed", khatazap.Error(khata.Wrap(k)))
+
+	logged := decode(t, buf)["error"].(map[string]interface{})
+	cause := logged["cause"].(map[string]interface{})
+	properties := cause["properties"].(map[string]interface{})
+
+	if cause["type"] != "HTTP" || cause["code"] != float64(404) || cause["message"] != "This is an error message" {
+		t.Error("Error() did not encode the context of the cause")
+		return
+	}
+
+	if properties["user_id"] != float64(12) || properties["password"] != khata.REDACTED_VALUE {
+		t.Error("Error() did not encode the serialized properties")
+		return
+	}
+
+	explanation := cause["explanations"].([]interface{})[0].(map[string]interface{})
+
+	if explanation["message"] != "This is an explanation" || explanation["fields"].(map[string]interface{})["attempt"] != float64(2) {
+		t.Error("Error() did not encode the explanations")
+		return
+	}
+
+	if len(logged["trace"].([]interface{})) == 0 {
+		t.Error("Error() did not encode the trace")
+		return
+	}
+}
+
+func TestNewCore(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	newLogger(buf).With(zap.String("service", "users")).Error("request failed", zap.Error(khata.New("This is an error message").SetCode(404)))
+
+	logged, ok := decode(t, buf)["error"].(map[string]interface{})
+
+	if !ok || logged["code"] != float64(404) {
+		t.Error("NewCore() did not encode the khata error of the error field")
+		return
+	}
+}
+
+func createError() *khata.Khata {
+	return khata.New("This is an error message").SetSeverity(khata.SeverityWarning)
+}
+
+func TestNewCoreLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	newLogger(buf).Info("request failed", zap.Error(createError()))
+	entry := decode(t, buf)
+
+	if entry["level"] != "info" {
+		t.Error("NewCore() changed the level of the logging call")
+		return
+	}
+
+	frame := entry["error"].(map[string]interface{})["trace"].([]interface{})[0].(map[string]interface{})
+
+	if frame["functionName"] != "github.com/cmseguin/khata/khatazap_test.createError" {
+		t.Error("NewCore() did not encode the creation trace of the error")
+		return
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for severity, level := range map[khata.Severity]zapcore.Level{
+		khata.SeverityDebug:    zapcore.DebugLevel,
+		khata.SeverityInfo:     zapcore.InfoLevel,
+		khata.SeverityWarning:  zapcore.WarnLevel,
+		khata.SeverityError:    zapcore.ErrorLevel,
+		khata.SeverityCritical: zapcore.ErrorLevel,
+		khata.SeverityFatal:    zapcore.ErrorLevel,
+	} {
+		if khatazap.Level(khata.New("This is an error message").SetSeverity(severity)) != level {
+			t.Error("Level() did not map " + severity.String() + " to " + level.String())
+			return
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package khatazerolog logs khata errors with zerolog. Errors are encoded field
+// by field on the event, without going through their JSON representation.
+//
+//	log.Error().Object("error", khatazerolog.Error(k)).Msg("request failed")
+//
+// Event starts the event at the level of the severity of the error instead:
+//
+//	khatazerolog.Event(&log, k).Msg("request failed")
+//
+// Install replaces zerolog.ErrorMarshalFunc so that the khata errors logged with
+// Err or AnErr are encoded the same way, while other errors are left untouched.
+package khatazerolog
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/cmseguin/khata"
+	"github.com/rs/zerolog"
+)
+
+var levels = map[khata.Severity]zerolog.Level{
+	khata.SeverityDebug:    zerolog.DebugLevel,
+	khata.SeverityInfo:     zerolog.InfoLevel,
+	khata.SeverityWarning:  zerolog.WarnLevel,
+	khata.SeverityError:    zerolog.ErrorLevel,
+	khata.SeverityCritical: zerolog.ErrorLevel,
+	khata.SeverityFatal:    zerolog.ErrorLevel,
+}
+
+// Returns the zerolog level of the severity of the error. Critical and fatal errors
+// are logged at the error level, exiting is left to HandleKhata.
+func Level(k *khata.Khata) zerolog.Level {
+	return levels[k.Severity()]
+}
+
+// Starts an event at the Level of the error, with the error under the "error" key
+func Event(logger *zerolog.Logger, k *khata.Khata) *zerolog.Event {
+	return logger.WithLevel(Level(k)).Object("error", khataObject{k})
+}
+
+// Returns the error as a zerolog object: message, type, code, exit code, severity,
+// properties, explanations, trace and cause
+func Error(k *khata.Khata) zerolog.LogObjectMarshaler {
+	return khataObject{k}
+}
+
+// Encodes the khata errors as objects and returns the other errors unchanged.
+// It has the signature of zerolog.ErrorMarshalFunc.
+func ErrorMarshalFunc(err error) interface{} {
+	var k *khata.Khata
+	if errors.As(err, &k) {
+		return khataObject{k}
+	}
+	return err
+}
+
+// Set zerolog.ErrorMarshalFunc to ErrorMarshalFunc. It should be called once,
+// before logging, as zerolog reads the variable without synchronization.
+func Install() {
+	zerolog.ErrorMarshalFunc = ErrorMarshalFunc
+}
+
+type khataObject struct {
+	k *khata.Khata
+}
+
+func (o khataObject) MarshalZerologObject(e *zerolog.Event) {
+	k := o.k
+
+	e.Str("message", k.Err.Error()).
+		Str("type", k.Type()).
+		Int("code", k.Code()).
+		Int("exitCode", k.ExitCode()).
+		Str("severity", k.Severity().String()).
+		Str("fingerprint", k.Fingerprint()).
+		Object("properties", properties{k})
+
+	if k.CodeName() != "" {
+		e.Str("codeName", k.CodeName())
+	}
+
+	if k.IsNonFatal() {
+		e.Bool("nonFatal", true)
+	}
+
+	if len(k.Explanations()) != 0 {
+		e.Array("explanations", explanations(k.Explanations()))
+	}
+
+	e.Array("trace", trace(k.CreationTrace()))
+
+	if cause := k.Cause(); cause != nil {
+		e.Object("cause", khataObject{cause})
+	}
+}
+
+type properties struct {
+	k *khata.Khata
+}
+
+func (p properties) MarshalZerologObject(e *zerolog.Event) {
+	p.k.RangeSerializedProperties(func(key string, value interface{}) bool {
+		addValue(e, key, value)
+		return true
+	})
+}
+
+type explanations []khata.KhataExplanation
+
+func (ex explanations) MarshalZerologArray(a *zerolog.Array) {
+	for _, explanation := range ex {
+		a.Object(explanationObject(explanation))
+	}
+}
+
+type explanationObject khata.KhataExplanation
+
+func (ex explanationObject) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("message", ex.Message).
+		Str("file", ex.File).
+		Int("line", ex.Line).
+		Str("functionName", ex.FunctionName).
+		Dur("elapsed", ex.Elapsed).
+		Uint64("goroutine", ex.Goroutine)
+
+	if len(ex.Fields) != 0 {
+		e.Object("fields", fields(ex.Fields))
+	}
+}
+
+type fields map[string]interface{}
+
+func (f fields) MarshalZerologObject(e *zerolog.Event) {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		addValue(e, key, f[key])
+	}
+}
+
+type trace []khata.KhataTrace
+
+func (t trace) MarshalZerologArray(a *zerolog.Array) {
+	for i := range t {
+		a.Object(frame{&t[i]})
+	}
+}
+
+type frame struct {
+	t *khata.KhataTrace
+}
+
+func (f frame) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("file", f.t.File()).
+		Int("line", f.t.Line()).
+		Str("functionName", f.t.FunctionName())
+}
+
+// Adds the value with the typed event methods when possible, to avoid reflection
+func addValue(e *zerolog.Event, key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		e.Str(key, v)
+	case int:
+		e.Int(key, v)
+	case int64:
+		e.Int64(key, v)
+	case int32:
+		e.Int32(key, v)
+	case uint:
+		e.Uint(key, v)
+	case uint64:
+		e.Uint64(key, v)
+	case float64:
+		e.Float64(key, v)
+	case bool:
+		e.Bool(key, v)
+	case time.Duration:
+		e.Dur(key, v)
+	case time.Time:
+		e.Time(key, v)
+	case *khata.Khata:
+		e.Object(key, khataObject{v})
+	case zerolog.LogObjectMarshaler:
+		e.Object(key, v)
+	case error:
+		e.Str(key, v.Error())
+	default:
+		e.Interface(key, v)
+	}
+}
@@ -0,0 +1,117 @@
+package khatazerolog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cmseguin/khata"
+	"github.com/cmseguin/khata/khatazerolog"
+	"github.com/rs/zerolog"
+)
+
+var userIDKey = khata.NewKey[int]("userID", khata.JSONName("user_id"))
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal("The logger did not write JSON: " + err.Error())
+	}
+	return entry
+}
+
+func TestError(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	k := khata.New("This is an error message").SetType("HTTP").SetCode(404)
+	userIDKey.Set(k, 12)
+	k.ExplainWith("This is an explanation", "attempt", 2)
+
+	logger := zerolog.New(buf)
+	logger.Error().Object("error", khatazerolog.Error(khata.Wrap(k))).Msg("request failed")
+
+	logged := decode(t, buf)["error"].(map[string]interface{})
+	cause := logged["cause"].(map[string]interface{})
+
+	if cause["type"] != "HTTP" || cause["code"] != float64(404) || cause["properties"].(map[string]interface{})["user_id"] != float64(12) {
+		t.Error("Error() did not encode the context of the cause")
+		return
+	}
+
+	explanation := cause["explanations"].([]interface{})[0].(map[string]interface{})
+
+	if explanation["message"] != "This is an explanation" || explanation["fields"].(map[string]interface{})["attempt"] != float64(2) {
+		t.Error("Error() did not encode the explanations")
+		return
+	}
+
+	if len(logged["trace"].([]interface{})) == 0 {
+		t.Error("Error() did not encode the trace")
+		return
+	}
+}
+
+func TestInstall(t *testing.T) {
+	previous := zerolog.ErrorMarshalFunc
+	defer func() { zerolog.ErrorMarshalFunc = previous }()
+
+	khatazerolog.Install()
+
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf)
+	logger.Error().Err(khata.New("This is an error message").SetCode(404)).Msg("request failed")
+
+	if logged, ok := decode(t, buf)["error"].(map[string]interface{}); !ok || logged["code"] != float64(404) {
+		t.Error("Install() did not encode the khata errors logged with Err")
+		return
+	}
+
+	buf.Reset()
+	logger.Error().Err(errors.New("This is an error message")).Msg("request failed")
+
+	if decode(t, buf)["error"] != "This is an error message" {
+		t.Error("Install() modified the encoding of other errors")
+		return
+	}
+}
+
+func createError() *khata.Khata {
+	return khata.New("This is an error message").SetSeverity(khata.SeverityWarning)
+}
+
+func TestEvent(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger := zerolog.New(buf)
+	khatazerolog.Event(&logger, createError()).Msg("request failed")
+	entry := decode(t, buf)
+
+	if entry["level"] != "warn" {
+		t.Error("Event() did not start the event at the level of the error")
+		return
+	}
+
+	frame := entry["error"].(map[string]interface{})["trace"].([]interface{})[0].(map[string]interface{})
+
+	if frame["functionName"] != "github.com/cmseguin/khata/khatazerolog_test.createError" {
+		t.Error("Event() did not encode the creation trace of the error")
+		return
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for severity, level := range map[khata.Severity]zerolog.Level{
+		khata.SeverityDebug:    zerolog.DebugLevel,
+		khata.SeverityInfo:     zerolog.InfoLevel,
+		khata.SeverityWarning:  zerolog.WarnLevel,
+		khata.SeverityError:    zerolog.ErrorLevel,
+		khata.SeverityCritical: zerolog.ErrorLevel,
+		khata.SeverityFatal:    zerolog.ErrorLevel,
+	} {
+		if khatazerolog.Level(khata.New("This is an error message").SetSeverity(severity)) != level {
+			t.Error("Level() did not map " + severity.String() + " to " + level.String())
+			return
+		}
+	}
+}
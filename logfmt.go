@@ -0,0 +1,132 @@
+package khata
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type logfmtOptions struct {
+	trace bool
+}
+
+// Option of the logfmt renderer
+type LogfmtOption func(*logfmtOptions)
+
+// Add the location the error was created at, as at=pkg.Func:123
+func LogfmtTrace() LogfmtOption {
+	return func(options *logfmtOptions) {
+		options.trace = true
+	}
+}
+
+// Returns the error rendered as a single logfmt line, without the trailing newline:
+//
+//	error="not found" type=HTTP code=404 exit=1 nonFatal=true severity=error explain="cache miss; db miss" prop.userID=12
+//
+// Explanations are joined with "; " in the order they were added, and properties
+// are sorted by key.
+func RenderLogfmt(k *Khata, options ...LogfmtOption) string {
+	return string(currentConfig().AppendLogfmt(nil, k, options...))
+}
+
+// Appends the logfmt rendering of the error to the buffer and returns the extended buffer
+func AppendLogfmt(buf []byte, k *Khata, options ...LogfmtOption) []byte {
+	return currentConfig().AppendLogfmt(buf, k, options...)
+}
+
+// Returns the error rendered as a single logfmt line, using this configuration
+func (c *Config) RenderLogfmt(k *Khata, options ...LogfmtOption) string {
+	return string(c.AppendLogfmt(nil, k, options...))
+}
+
+// Appends the logfmt rendering of the error to the buffer, using this configuration
+func (c *Config) AppendLogfmt(buf []byte, k *Khata, options ...LogfmtOption) []byte {
+	opts := logfmtOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	buf = appendLogfmtPair(buf, "error", fmt.Sprint(k.Err))
+	buf = appendLogfmtPair(buf, "type", k.errorType)
+	buf = appendLogfmtPair(buf, "code", strconv.Itoa(k.errorCode))
+	if k.codeName != "" {
+		buf = appendLogfmtPair(buf, "codeName", k.codeName)
+	}
+	buf = appendLogfmtPair(buf, "exit", strconv.Itoa(k.exitCode))
+	if k.nonFatal {
+		buf = appendLogfmtPair(buf, "nonFatal", "true")
+	}
+	buf = appendLogfmtPair(buf, "severity", k.Severity().String())
+
+	if opts.trace {
+		if trace := c.frames(k.CreationTrace()); len(trace) != 0 {
+			buf = appendLogfmtPair(buf, "at", fmt.Sprintf("%s:%d", c.trimFunc(trace[0].functionName), trace[0].line))
+		}
+	}
+
+	if len(k.explanationStack) != 0 {
+		messages := make([]string, len(k.explanationStack))
+		for i, explanation := range k.explanationStack {
+			messages[i] = explanation.Message
+		}
+		buf = appendLogfmtPair(buf, "explain", strings.Join(messages, "; "))
+	}
+
+	properties := renderProperties(k.properties, k.meta, false)
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		buf = appendLogfmtPair(buf, "prop."+logfmtKey(key), fmt.Sprint(properties[key]))
+	}
+
+	return buf
+}
+
+func appendLogfmtPair(buf []byte, key string, value string) []byte {
+	if len(buf) != 0 {
+		buf = append(buf, ' ')
+	}
+
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+
+	if needsLogfmtQuoting(value) {
+		return strconv.AppendQuote(buf, value)
+	}
+
+	return append(buf, value...)
+}
+
+// Returns true if the value is empty or contains characters that would break the pair
+func needsLogfmtQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replaces the characters that are not allowed in a logfmt key
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
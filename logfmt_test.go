@@ -0,0 +1,50 @@
+package khata_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestRenderLogfmt(t *testing.T) {
+	k := khata.New("This is an \"error\" message").
+		SetType("HTTP").
+		SetCode(404).
+		SetNonFatal().
+		SetProperty("userID", 12).
+		SetProperty("path", "/users/12").
+		SetProperty("empty", "")
+	k.Explain("This is an explanation")
+	k.Explain("key=value")
+
+	expected := `error="This is an \"error\" message" type=HTTP code=404 exit=1 nonFatal=true severity=error explain="This is an explanation; key=value" prop.empty="" prop.path=/users/12 prop.userID=12`
+
+	if khata.RenderLogfmt(k) != expected {
+		t.Error("RenderLogfmt() returned " + khata.RenderLogfmt(k))
+		return
+	}
+
+	if !strings.HasPrefix(string(khata.AppendLogfmt([]byte("level=error"), k)), "level=error error=") {
+		t.Error("AppendLogfmt() did not append to the buffer")
+		return
+	}
+
+	if !strings.Contains(khata.RenderLogfmt(k, khata.LogfmtTrace()), "khata_test.TestRenderLogfmt:") {
+		t.Error("RenderLogfmt() did not render the location of the error")
+		return
+	}
+}
+
+func createLogfmtError() *khata.Khata {
+	return khata.New("This is an error message")
+}
+
+func TestRenderLogfmtCreationTrace(t *testing.T) {
+	k := createLogfmtError()
+
+	if !strings.Contains(khata.RenderLogfmt(k, khata.LogfmtTrace()), "at=github.com/cmseguin/khata_test.createLogfmtError:") {
+		t.Error("RenderLogfmt() did not render the location the error was created at")
+		return
+	}
+}
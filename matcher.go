@@ -0,0 +1,217 @@
+package khata
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Matcher selects khata errors. Matchers are combined with And, Or and Not,
+// and describe themselves with String() for debugging:
+//
+//	retryable := khata.And(khata.Template(HttpError), khata.Not(khata.Code(400, 404)))
+//	retryable.String() // and(template(type=HTTP code=-1), not(code(400, 404)))
+type Matcher interface {
+	// Returns true if the error is selected. The causes of the error are not considered, see MatchError.
+	Match(k *Khata) bool
+	String() string
+}
+
+type matcher struct {
+	match       func(k *Khata) bool
+	description string
+}
+
+func (m matcher) Match(k *Khata) bool {
+	return m.match(k)
+}
+
+func (m matcher) String() string {
+	return m.description
+}
+
+// Matches the errors created from the template or one of its children
+func Template(template *KhataTemplate) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.IsRelatedTo(template)
+		},
+		description: fmt.Sprintf("template(type=%s code=%d)", template.Type(), template.Code()),
+	}
+}
+
+// Matches the errors with any of the codes
+func Code(codes ...int) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.IsAnyCode(codes...)
+		},
+		description: "code(" + joinValues(codes) + ")",
+	}
+}
+
+// Matches the errors whose code matches any of the patterns, like AUTH.* or 404.
+// See KhataCode.Matches.
+func CodePattern(patterns ...string) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			for _, pattern := range patterns {
+				if k.MatchCode(pattern) {
+					return true
+				}
+			}
+			return false
+		},
+		description: "codePattern(" + joinValues(patterns) + ")",
+	}
+}
+
+// Matches the errors with any of the types
+func Type(errorTypes ...string) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.IsAnyType(errorTypes...)
+		},
+		description: "type(" + joinValues(errorTypes) + ")",
+	}
+}
+
+// Matches the errors with any of the exit codes
+func ExitCode(codes ...int) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.IsAnyExitCode(codes...)
+		},
+		description: "exitCode(" + joinValues(codes) + ")",
+	}
+}
+
+// Matches the errors whose severity is at least the given one
+func MinSeverity(severity Severity) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.Severity() >= severity
+		},
+		description: "minSeverity(" + severity.String() + ")",
+	}
+}
+
+// Matches the errors with the property set to the value, compared with reflect.DeepEqual
+func Property(key string, value interface{}) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			actual, ok := k.properties[key]
+			return ok && reflect.DeepEqual(actual, value)
+		},
+		description: fmt.Sprintf("property(%s=%v)", key, value),
+	}
+}
+
+// Matches the errors with the property set, whatever its value
+func HasProperty(key string) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return k.HasProperty(key)
+		},
+		description: "hasProperty(" + key + ")",
+	}
+}
+
+// Matches every error
+func Always() Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return true
+		},
+		description: "always",
+	}
+}
+
+// Matches the errors selected by every matcher
+func And(matchers ...Matcher) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			for _, m := range matchers {
+				if !m.Match(k) {
+					return false
+				}
+			}
+			return true
+		},
+		description: "and(" + joinValues(matchers) + ")",
+	}
+}
+
+// Matches the errors selected by any of the matchers
+func Or(matchers ...Matcher) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			for _, m := range matchers {
+				if m.Match(k) {
+					return true
+				}
+			}
+			return false
+		},
+		description: "or(" + joinValues(matchers) + ")",
+	}
+}
+
+// Matches the errors not selected by the matcher
+func Not(m Matcher) Matcher {
+	return matcher{
+		match: func(k *Khata) bool {
+			return !m.Match(k)
+		},
+		description: "not(" + m.String() + ")",
+	}
+}
+
+// Returns the first khata error of the error tree selected by the matcher, or nil.
+// The tree is walked like errors.As does, through wrap chains and errors.Join,
+// starting with the outermost error.
+func MatchError(m Matcher, err error) *Khata {
+	var found *Khata
+
+	walkErrors(err, func(k *Khata) bool {
+		if m.Match(k) {
+			found = k
+			return false
+		}
+		return true
+	})
+
+	return found
+}
+
+// Calls fn for every khata error of the tree, in pre-order, until fn returns false
+func walkErrors(err error, fn func(k *Khata) bool) bool {
+	if err == nil {
+		return true
+	}
+
+	if k, ok := err.(*Khata); ok && !fn(k) {
+		return false
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if !walkErrors(inner, fn) {
+				return false
+			}
+		}
+	case interface{ Unwrap() error }:
+		return walkErrors(e.Unwrap(), fn)
+	}
+
+	return true
+}
+
+func joinValues[T any](values []T) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, ", ")
+}
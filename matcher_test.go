@@ -0,0 +1,82 @@
+package khata_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestMatcher(t *testing.T) {
+	httpError := khata.NewTemplate().SetType("HTTP").SetNonFatal()
+	notFound := httpError.Extend().SetCode(404)
+
+	k := notFound.New().SetProperty("retryable", true)
+
+	retryable := khata.And(khata.Template(httpError), khata.Not(khata.Code(400, 410)), khata.Property("retryable", true))
+
+	if !retryable.Match(k) {
+		t.Error("And() did not match the error")
+		return
+	}
+
+	if khata.Or(khata.Type("Database"), khata.MinSeverity(khata.SeverityCritical)).Match(k) {
+		t.Error("Or() matched the error")
+		return
+	}
+
+	if retryable.String() != "and(template(type=HTTP code=-1), not(code(400, 410)), property(retryable=true))" {
+		t.Error("String() did not describe the matcher: " + retryable.String())
+		return
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	notFound := khata.NewTemplate().SetType("HTTP").SetCode(404)
+	cause := notFound.New()
+
+	err := errors.Join(
+		errors.New("This is an error message"),
+		fmt.Errorf("request failed: %w", khata.Wrap(cause)),
+	)
+
+	if khata.MatchError(khata.Template(notFound), err) != cause {
+		t.Error("MatchError() did not find the cause through errors.Join and the wrap chain")
+		return
+	}
+
+	if khata.MatchError(khata.Code(500), err) != nil {
+		t.Error("MatchError() returned an error that does not match")
+		return
+	}
+}
+
+func TestRouter(t *testing.T) {
+	notFound := khata.NewTemplate().SetType("HTTP").SetCode(404)
+
+	statuses := khata.NewRouter[int]().
+		On(khata.Template(notFound), func(k *khata.Khata) int { return k.Code() }).
+		On(khata.Property("retryable", true), func(k *khata.Khata) int { return 503 }).
+		Default(func(err error) int { return 500 })
+
+	if status, ok := statuses.Route(fmt.Errorf("lookup: %w", notFound.New())); !ok || status != 404 {
+		t.Error("Route() did not dispatch the error to the first matching route")
+		return
+	}
+
+	if status, _ := statuses.Route(khata.New("This is an error message").SetProperty("retryable", true)); status != 503 {
+		t.Error("Route() did not dispatch the error to the matching route")
+		return
+	}
+
+	if status, _ := statuses.Route(errors.New("This is an error message")); status != 500 {
+		t.Error("Route() did not dispatch the error to the default handler")
+		return
+	}
+
+	if _, ok := statuses.Route(nil); ok {
+		t.Error("Route() dispatched a nil error")
+		return
+	}
+}
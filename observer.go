@@ -0,0 +1,86 @@
+package khata
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Observer is notified of the life cycle of khata errors. It is the extension
+// point used by metrics and reporters.
+//
+// ErrorCreated is called when an error is created with New, Wrap or a template,
+// before the setters of a chain are applied, so errors only carry the context
+// given by their template at that time. ErrorHandled is called the first time an
+// error is marked as handled (see MarkHandled and HandleKhata).
+//
+// Observers are called synchronously and must be safe for concurrent use.
+type Observer interface {
+	ErrorCreated(k *Khata)
+	ErrorHandled(k *Khata)
+}
+
+// Observers are registered behind a pointer so any observer, comparable or not, can be removed
+type observerEntry struct {
+	observer Observer
+}
+
+var (
+	observersMutex sync.Mutex
+	// Copied on write so notifying does not need to lock
+	observers atomic.Pointer[[]*observerEntry]
+)
+
+// Register an observer. The returned function removes it.
+func AddObserver(observer Observer) (remove func()) {
+	observersMutex.Lock()
+	defer observersMutex.Unlock()
+
+	entry := &observerEntry{observer: observer}
+
+	var updated []*observerEntry
+	if current := observers.Load(); current != nil {
+		updated = append(updated, *current...)
+	}
+	updated = append(updated, entry)
+	observers.Store(&updated)
+
+	return func() {
+		removeObserver(entry)
+	}
+}
+
+func removeObserver(entry *observerEntry) {
+	observersMutex.Lock()
+	defer observersMutex.Unlock()
+
+	current := observers.Load()
+	if current == nil {
+		return
+	}
+
+	updated := make([]*observerEntry, 0, len(*current))
+
+	for _, e := range *current {
+		if e != entry {
+			updated = append(updated, e)
+		}
+	}
+
+	observers.Store(&updated)
+}
+
+func notifyCreated(k *Khata) {
+	if current := observers.Load(); current != nil {
+		for _, entry := range *current {
+			entry.observer.ErrorCreated(k)
+		}
+	}
+}
+
+func notifyHandled(k *Khata) {
+	if current := observers.Load(); current != nil {
+		for _, entry := range *current {
+			entry.observer.ErrorHandled(k)
+		}
+	}
+}
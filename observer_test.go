@@ -0,0 +1,51 @@
+package khata_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+type recordingObserver struct {
+	created []*khata.Khata
+	handled []*khata.Khata
+}
+
+func (o *recordingObserver) ErrorCreated(k *khata.Khata) {
+	o.created = append(o.created, k)
+}
+
+func (o *recordingObserver) ErrorHandled(k *khata.Khata) {
+	o.handled = append(o.handled, k)
+}
+
+func TestObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	remove := khata.AddObserver(observer)
+
+	k := khata.New("This is an error message")
+	khata.NewTemplate().Wrap(errors.New("This is an error message"))
+	khata.Wrap(k)
+
+	if len(observer.created) != 3 {
+		t.Error("AddObserver() was not notified of every created error")
+		return
+	}
+
+	k.MarkHandled()
+	k.MarkHandled()
+
+	if len(observer.handled) != 1 || observer.handled[0] != k {
+		t.Error("AddObserver() was not notified once of the handled error")
+		return
+	}
+
+	remove()
+	khata.New("This is an error message")
+
+	if len(observer.created) != 3 {
+		t.Error("AddObserver() remove function did not remove the observer")
+		return
+	}
+}
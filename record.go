@@ -0,0 +1,121 @@
+package khata
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// KhataRecord describes a khata error decoded from a serialized representation.
+// It is used by decoders to rebuild errors with Restore.
+type KhataRecord struct {
+	Message  string
+	Type     string
+	Code     int
+	CodeName string
+	// Exit code of the error. NON_FATAL_EXIT_CODE is accepted as a legacy
+	// encoding of NonFatal, and other invalid codes are replaced by DEFAULT_EXIT_CODE.
+	ExitCode     int
+	NonFatal     bool
+	Severity     Severity
+	CreatedAt    time.Time
+	HandledAt    time.Time
+	Properties   map[string]interface{}
+	Explanations []KhataExplanation
+	Trace        []KhataTrace
+	// Goroutine and process context, nil when it was not captured
+	Environment *KhataEnvironment
+	// Goroutine dump attached to the error
+	Goroutines []KhataGoroutineGroup
+	// The khata error wrapped by this one, nil for the root cause
+	Cause *KhataRecord
+}
+
+// Rebuild a khata error and its causes from a record. Restored errors keep the
+// trace and handling time of the record instead of computing them again.
+func Restore(record *KhataRecord) *Khata {
+	var err error = errors.New(record.Message)
+
+	if record.Cause != nil {
+		err = &restoredError{message: record.Message, cause: Restore(record.Cause)}
+	}
+
+	k := wrap(err).
+		SetType(record.Type).
+		SetCode(record.Code).
+		SetCodeName(record.CodeName)
+
+	// Decoded data is not trusted like the exit codes chosen by the program
+	if ValidExitCode(record.ExitCode) {
+		k.exitCode = record.ExitCode
+	}
+	k.nonFatal = record.NonFatal || record.ExitCode == NON_FATAL_EXIT_CODE
+
+	k.severity = record.Severity
+	k.createdAt = record.CreatedAt.UTC()
+	k.traceFrozen = true
+	k.traceStack = append([]KhataTrace{}, record.Trace...)
+	k.explanationStack = append([]KhataExplanation{}, record.Explanations...)
+	k.environment = record.Environment
+	k.goroutines = record.Goroutines
+
+	if !record.HandledAt.IsZero() {
+		k.handledAt = record.HandledAt.UTC()
+	}
+
+	for key, value := range record.Properties {
+		k.properties[key] = value
+	}
+
+	return k
+}
+
+// Error restored from a record with a cause. The message of the record already
+// includes the message of the cause, and of the layers between them that were
+// not serialized, like fmt.Errorf("loading user: %w", cause).
+type restoredError struct {
+	message string
+	cause   *Khata
+}
+
+func (e *restoredError) Error() string {
+	return e.message
+}
+
+func (e *restoredError) Unwrap() error {
+	return e.cause
+}
+
+// Returns the properties as they are serialized: redacted values are hidden
+// and keys are renamed with their JSON name. See Key.
+func (k *Khata) SerializedProperties() map[string]interface{} {
+	return renderProperties(k.properties, k.meta, true)
+}
+
+// Calls fn for every serialized property, sorted by property key, until fn returns false.
+// Unlike SerializedProperties, it does not build an intermediate map.
+func (k *Khata) RangeSerializedProperties(fn func(key string, value interface{}) bool) {
+	keys := make([]string, 0, len(k.properties))
+	for key := range k.properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := k.properties[key]
+		name := key
+
+		if m, ok := k.meta[key]; ok {
+			if m.redacted {
+				value = REDACTED_VALUE
+			}
+			if m.jsonName != "" {
+				name = m.jsonName
+			}
+		}
+
+		if !fn(name, value) {
+			return
+		}
+	}
+}
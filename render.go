@@ -0,0 +1,327 @@
+package khata
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Layout of the timestamps in the rendered errors
+const debugTimeLayout = "2006/01/02 15:04:05 0.000ms"
+
+type reportEntry struct {
+	Key   string
+	Value string
+}
+
+type reportExplanation struct {
+	Elapsed   string
+	Delta     string
+	Location  string
+	Function  string
+	Goroutine uint64
+	Message   string
+	Fields    []reportEntry
+}
+
+type reportTrace struct {
+	Location string
+	Function string
+	Folded   int
+	OwnCode  bool
+}
+
+type reportCause struct {
+	Message      string
+	Type         string
+	Code         string
+	Explanations []reportExplanation
+	Properties   []reportEntry
+}
+
+// report holds the content shared by the markdown and html renderers,
+// with the paths and function names already trimmed
+type report struct {
+	Message      string
+	Severity     string
+	Explanations []reportExplanation
+	Trace        []reportTrace
+	Details      []reportEntry
+	Environment  []reportEntry
+	Properties   []reportEntry
+	Causes       []reportCause
+}
+
+// Returns the report of the error, with the given trace
+func (c *Config) newReport(k *Khata, trace []KhataTrace) report {
+	handledAt := k.handledTime()
+
+	r := report{
+		Message:      fmt.Sprint(k.Err),
+		Severity:     k.Severity().String(),
+		Explanations: c.reportExplanations(k.explanationStack),
+		Details: []reportEntry{
+			{"Error Type", k.errorType},
+			{"Error Code", k.ErrorCode().String()},
+			{"Exit Code", k.describeExitCode()},
+			{"Severity", k.Severity().String()},
+			{"Error At", k.createdAt.Format(debugTimeLayout)},
+			{"Handled At", handledAt.Format(debugTimeLayout)},
+			{"Enlapse Time", fmt.Sprintf("%.3fs", float64(handledAt.Sub(k.createdAt).Milliseconds())/1000)},
+		},
+		Properties: reportProperties(renderProperties(k.properties, k.meta, false)),
+		Causes:     []reportCause{},
+	}
+
+	if k.environment != nil {
+		r.Environment = reportProperties(k.environment.entries())
+	}
+
+	r.Trace = c.reportTrace(trace)
+
+	for _, cause := range k.Chain()[1:] {
+		r.Causes = append(r.Causes, reportCause{
+			Message:      fmt.Sprint(cause.Err),
+			Type:         cause.errorType,
+			Code:         cause.ErrorCode().String(),
+			Explanations: c.reportExplanations(cause.explanationStack),
+			Properties:   reportProperties(renderProperties(cause.properties, cause.meta, false)),
+		})
+	}
+
+	return r
+}
+
+func (c *Config) reportTrace(trace []KhataTrace) []reportTrace {
+	result := []reportTrace{}
+
+	for _, trace := range c.frames(trace) {
+		result = append(result, c.reportFrame(trace))
+	}
+
+	return result
+}
+
+func (c *Config) reportFrame(trace KhataTrace) reportTrace {
+	return reportTrace{
+		Location: fmt.Sprintf("%s:%d", c.trimPath(trace.file), trace.line),
+		Function: c.trimFunc(trace.functionName),
+		Folded:   trace.folded,
+		OwnCode:  trace.IsOwnCode(),
+	}
+}
+
+func (c *Config) reportExplanations(explanations []KhataExplanation) []reportExplanation {
+	result := []reportExplanation{}
+	var previous time.Duration
+
+	for _, explanation := range explanations {
+		result = append(result, reportExplanation{
+			Elapsed:   "+" + formatDuration(explanation.Elapsed),
+			Delta:     formatDuration(explanation.Elapsed - previous),
+			Location:  fmt.Sprintf("%s:%d", c.trimPath(explanation.File), explanation.Line),
+			Function:  c.trimFunc(explanation.FunctionName),
+			Goroutine: explanation.Goroutine,
+			Message:   explanation.Message,
+			Fields:    reportProperties(explanation.Fields),
+		})
+		previous = explanation.Elapsed
+	}
+
+	return result
+}
+
+func reportProperties(properties map[string]interface{}) []reportEntry {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]reportEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, reportEntry{key, fmt.Sprint(properties[key])})
+	}
+
+	return entries
+}
+
+// Returns the error rendered as markdown, to paste it in issue trackers or chats.
+// It contains the same sections as Debug().
+func RenderMarkdown(k *Khata) string {
+	return currentConfig().RenderMarkdown(k)
+}
+
+// Returns the error rendered as markdown, using this configuration
+func (c *Config) RenderMarkdown(k *Khata) string {
+	r := c.newReport(k, k.Trace())
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "## %s\n", escapeMarkdown(r.Message))
+
+	b.WriteString("\n### Explanations\n\n")
+	writeMarkdownExplanations(b, r.Explanations)
+
+	b.WriteString("\n### Trace\n\n```\n")
+	for _, trace := range r.Trace {
+		fmt.Fprintf(b, "%s (%s)", trace.Location, trace.Function)
+		if trace.Folded != 0 {
+			fmt.Fprintf(b, " +%d folded frames", trace.Folded)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("```\n")
+
+	b.WriteString("\n### Details\n\n")
+	for _, detail := range r.Details {
+		fmt.Fprintf(b, "- **%s**: %s\n", detail.Key, escapeMarkdown(detail.Value))
+	}
+
+	if len(r.Environment) != 0 {
+		b.WriteString("\n### Environment\n\n")
+		for _, entry := range r.Environment {
+			fmt.Fprintf(b, "- **%s**: %s\n", escapeMarkdown(entry.Key), escapeMarkdown(entry.Value))
+		}
+	}
+
+	if len(r.Properties) != 0 {
+		b.WriteString("\n### Properties\n\n")
+		writeMarkdownProperties(b, r.Properties)
+	}
+
+	for _, cause := range r.Causes {
+		fmt.Fprintf(b, "\n### Caused by %s (%s, code %s)\n\n", escapeMarkdown(cause.Message), escapeMarkdown(cause.Type), escapeMarkdown(cause.Code))
+		writeMarkdownExplanations(b, cause.Explanations)
+
+		if len(cause.Properties) != 0 {
+			b.WriteString("\n**Properties**\n\n")
+			writeMarkdownProperties(b, cause.Properties)
+		}
+	}
+
+	return b.String()
+}
+
+func writeMarkdownExplanations(b *strings.Builder, explanations []reportExplanation) {
+	if len(explanations) == 0 {
+		b.WriteString("_No explanations_\n")
+		return
+	}
+
+	b.WriteString("| Elapsed | Δ | Location | Function | Goroutine | Message |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+
+	for _, explanation := range explanations {
+		// Every cell is escaped, so pipes and newlines cannot break the row
+		message := escapeMarkdown(explanation.Message)
+		for _, field := range explanation.Fields {
+			message += " " + escapeMarkdown(field.Key+"="+field.Value)
+		}
+
+		fmt.Fprintf(
+			b,
+			"| %s | %s | %s | %s | %d | %s |\n",
+			escapeMarkdown(explanation.Elapsed),
+			escapeMarkdown(explanation.Delta),
+			escapeMarkdown(explanation.Location),
+			escapeMarkdown(explanation.Function),
+			explanation.Goroutine,
+			message,
+		)
+	}
+}
+
+func writeMarkdownProperties(b *strings.Builder, properties []reportEntry) {
+	b.WriteString("| Key | Value |\n")
+	b.WriteString("| --- | --- |\n")
+
+	for _, property := range properties {
+		fmt.Fprintf(b, "| %s | %s |\n", escapeMarkdown(property.Key), escapeMarkdown(property.Value))
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"[", "\\[",
+	"]", "\\]",
+	"<", "\\<",
+	">", "\\>",
+	"#", "\\#",
+	"|", "\\|",
+	"\n", " ",
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var htmlReport = template.Must(template.New("khata").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Message}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h1.error, h1.fatal { color: #c0392b; } h1.warning { color: #d68910; } h1.info, h1.debug { color: #2471a3; }
+h2 { font-size: 1.1em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+code { font-family: ui-monospace, monospace; font-size: 0.9em; }
+tr.own code { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1 class="{{.Severity}}">{{.Message}}</h1>
+<h2>Explanations</h2>
+{{template "explanations" .Explanations}}
+<h2>Trace</h2>
+<table>
+{{range .Trace}}<tr{{if .OwnCode}} class="own"{{end}}><td><code>{{.Location}}</code></td><td><code>{{.Function}}</code>{{if .Folded}} <em>+{{.Folded}} folded frames</em>{{end}}</td></tr>
+{{end}}</table>
+<h2>Details</h2>
+{{template "entries" .Details}}
+{{if .Environment}}<h2>Environment</h2>
+{{template "entries" .Environment}}{{end}}
+{{if .Properties}}<h2>Properties</h2>
+{{template "entries" .Properties}}{{end}}
+{{range .Causes}}<h2>Caused by {{.Message}} ({{.Type}}, code {{.Code}})</h2>
+{{template "explanations" .Explanations}}
+{{if .Properties}}{{template "entries" .Properties}}{{end}}
+{{end}}</body>
+</html>
+{{define "explanations"}}{{if .}}<table>
+<tr><th>Elapsed</th><th>Δ</th><th>Location</th><th>Function</th><th>Goroutine</th><th>Message</th></tr>
+{{range .}}<tr><td>{{.Elapsed}}</td><td>{{.Delta}}</td><td><code>{{.Location}}</code></td><td><code>{{.Function}}</code></td><td>{{.Goroutine}}</td><td>{{.Message}}{{range .Fields}} <code>{{.Key}}={{.Value}}</code>{{end}}</td></tr>
+{{end}}</table>{{else}}<p><em>No explanations</em></p>{{end}}{{end}}
+{{define "entries"}}<table>
+{{range .}}<tr><th>{{.Key}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}`))
+
+// Returns the error rendered as an html page, for development error pages.
+// It contains the same sections as Debug(), with every value escaped.
+// KhataDevServer.Middleware serves it for the errors of an http.Handler.
+func RenderHTML(k *Khata) string {
+	return currentConfig().RenderHTML(k)
+}
+
+// Returns the error rendered as an html page, using this configuration
+func (c *Config) RenderHTML(k *Khata) string {
+	return c.renderHTML(k, k.Trace())
+}
+
+func (c *Config) renderHTML(k *Khata, trace []KhataTrace) string {
+	b := &bytes.Buffer{}
+
+	if err := htmlReport.Execute(b, c.newReport(k, trace)); err != nil {
+		return template.HTMLEscapeString(err.Error())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package khata_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	k := khata.New("This is an *error* message").SetType("HTTP").SetProperty("userID", 12)
+	k.ExplainWith("This is an explanation", "attempt", 2)
+	k.ExplainWith("Either a|b", "choice", "a|b")
+	k.ExplainWith("This is a multiline field", "stack", "a\nb")
+	wrapped := khata.Wrap(k)
+
+	md := khata.RenderMarkdown(wrapped)
+
+	for _, expected := range []string{
+		"### Explanations",
+		"### Trace",
+		"- **Error Type**: KhataError",
+		"### Caused by This is an \\*error\\* message (HTTP, code -1)",
+		"This is an explanation attempt=2 |",
+		"| Either a\\|b choice=a\\|b |",
+		"stack=a b |",
+		"| userID | 12 |",
+	} {
+		if !strings.Contains(md, expected) {
+			t.Error("RenderMarkdown() did not render " + expected)
+			return
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	k := khata.New("<script>alert(1)</script>").SetProperty("userID", 12)
+	k.Explain("This is an explanation")
+
+	page := khata.RenderHTML(k)
+
+	if strings.Contains(page, "<script>") {
+		t.Error("RenderHTML() did not escape the error message")
+		return
+	}
+
+	for _, expected := range []string{"&lt;script&gt;", "This is an explanation", "<th>userID</th><td>12</td>", "render_test.go"} {
+		if !strings.Contains(page, expected) {
+			t.Error("RenderHTML() did not render " + expected)
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package khata
+
+import "strings"
+
+type route[T any] struct {
+	matcher Matcher
+	handler func(k *Khata) T
+}
+
+// Router dispatches errors to the handler of the first route matching a khata
+// error of their tree. It can map errors to HTTP statuses, gRPC codes or
+// reporters:
+//
+//	statuses := khata.NewRouter[int]().
+//		On(khata.Template(NotFound), func(k *khata.Khata) int { return 404 }).
+//		On(khata.Property("retryable", true), func(k *khata.Khata) int { return 503 }).
+//		Default(func(err error) int { return 500 })
+//
+//	status, _ := statuses.Route(err)
+type Router[T any] struct {
+	routes   []route[T]
+	fallback func(err error) T
+}
+
+// Create a router without routes
+func NewRouter[T any]() *Router[T] {
+	return &Router[T]{}
+}
+
+// Add a route. Routes are tried in the order they were added.
+func (r *Router[T]) On(m Matcher, handler func(k *Khata) T) *Router[T] {
+	r.routes = append(r.routes, route[T]{matcher: m, handler: handler})
+	return r
+}
+
+// Set the handler of the errors matching no route
+func (r *Router[T]) Default(handler func(err error) T) *Router[T] {
+	r.fallback = handler
+	return r
+}
+
+// Dispatch the error to the first matching route, or to the default handler.
+// The handlers receive the khata error selected by the matcher, which can be a
+// cause of the error. Returns false if no handler was called, which is always
+// the case for nil errors.
+func (r *Router[T]) Route(err error) (T, bool) {
+	var zero T
+
+	if err == nil {
+		return zero, false
+	}
+
+	for _, route := range r.routes {
+		if k := MatchError(route.matcher, err); k != nil {
+			return route.handler(k), true
+		}
+	}
+
+	if r.fallback != nil {
+		return r.fallback(err), true
+	}
+
+	return zero, false
+}
+
+// Returns the matchers of the routes, in order
+func (r *Router[T]) String() string {
+	lines := make([]string, 0, len(r.routes)+1)
+
+	for _, route := range r.routes {
+		lines = append(lines, route.matcher.String())
+	}
+
+	if r.fallback != nil {
+		lines = append(lines, "default")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,92 @@
+package khata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity describes how bad an error is, independently of the exit code
+// that will be used if the program ends because of it.
+type Severity int
+
+const (
+	// SeverityUnset means no severity was set explicitly. The effective
+	// severity is then derived from the non-fatal flag (see Khata.Severity).
+	SeverityUnset Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+	SeverityFatal
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnset:    "unset",
+	SeverityDebug:    "debug",
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+	SeverityFatal:    "fatal",
+}
+
+// Returns the lowercase name of the severity
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Parse a severity from its name. Returns SeverityUnset and false if the name is unknown.
+func ParseSeverity(name string) (Severity, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if name == "warn" {
+		return SeverityWarning, true
+	}
+
+	for severity, severityName := range severityNames {
+		if severityName == name {
+			return severity, true
+		}
+	}
+
+	return SeverityUnset, false
+}
+
+// Returns true if the severity is at least as severe as the given one
+func (s Severity) AtLeast(other Severity) bool {
+	return s >= other
+}
+
+// Implements encoding.TextMarshaler so severities are serialized by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Implements encoding.TextUnmarshaler
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, ok := ParseSeverity(string(text))
+	if !ok {
+		return fmt.Errorf("unknown severity: %q", string(text))
+	}
+	*s = severity
+	return nil
+}
+
+// Returns the effective severity for the given explicit severity and non-fatal flag.
+// Without an explicit severity, non-fatal errors map to SeverityError and the
+// others map to SeverityFatal, which matches the historical IsFatal behavior.
+func resolveSeverity(severity Severity, nonFatal bool) Severity {
+	if severity != SeverityUnset {
+		return severity
+	}
+
+	if nonFatal {
+		return SeverityError
+	}
+
+	return SeverityFatal
+}
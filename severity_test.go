@@ -0,0 +1,73 @@
+package khata_test
+
+import (
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestKhataSeverityDefaults(t *testing.T) {
+	k := khata.New("This is an error message")
+
+	if k.Severity() != khata.SeverityFatal {
+		t.Error("Severity() did not default to fatal for the default exit code")
+		return
+	}
+
+	k.SetNonFatal()
+
+	if k.Severity() != khata.SeverityError || k.IsFatal() {
+		t.Error("Severity() did not resolve the non-fatal exit code to error")
+		return
+	}
+
+	k.SetSeverity(khata.SeverityFatal)
+
+	if !k.IsFatal() {
+		t.Error("IsFatal() did not honor the explicit severity")
+		return
+	}
+}
+
+func TestKhataSeverityFromTemplate(t *testing.T) {
+	template := khata.NewTemplate().SetSeverity(khata.SeverityWarning)
+	template2 := template.Extend()
+
+	if template2.Severity() != khata.SeverityWarning {
+		t.Error("Extend() did not keep the severity")
+		return
+	}
+
+	k := template2.New()
+
+	if k.Severity() != khata.SeverityWarning || k.IsFatal() {
+		t.Error("New() did not set the severity")
+		return
+	}
+
+	if !k.IsSeverity(khata.SeverityInfo) || k.IsSeverity(khata.SeverityError) {
+		t.Error("IsSeverity() did not compare the severities")
+		return
+	}
+
+	k2 := khata.NewTemplate().SetSeverity(khata.SeverityCritical).Apply(khata.New("other"))
+
+	if k2.Severity() != khata.SeverityCritical {
+		t.Error("Apply() did not set the severity")
+		return
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, ok := khata.ParseSeverity("Warn")
+
+	if !ok || severity != khata.SeverityWarning {
+		t.Error("ParseSeverity() did not parse the warning alias")
+		return
+	}
+
+	if _, ok := khata.ParseSeverity("nope"); ok {
+		t.Error("ParseSeverity() accepted an unknown severity")
+		return
+	}
+}
@@ -0,0 +1,73 @@
+// Package sysexits defines the exit codes of BSD sysexits.h, and a template
+// for each of them, so command line tools exit with consistent statuses.
+//
+//	if len(args) != 1 {
+//		return sysexits.UsageError.New("expected one file")
+//	}
+//
+// The templates can be extended to add a code or properties, and keep their exit code.
+package sysexits
+
+import "github.com/cmseguin/khata"
+
+// Exit codes of sysexits.h
+const (
+	// Successful termination
+	EX_OK = 0
+	// The command was used incorrectly: wrong number of arguments, bad flag, bad syntax
+	EX_USAGE = 64
+	// The input data was incorrect in some way
+	EX_DATAERR = 65
+	// An input file did not exist or was not readable
+	EX_NOINPUT = 66
+	// The user specified did not exist
+	EX_NOUSER = 67
+	// The host specified did not exist
+	EX_NOHOST = 68
+	// A service is unavailable, or something does not work and the reason is unknown
+	EX_UNAVAILABLE = 69
+	// An internal software error has been detected
+	EX_SOFTWARE = 70
+	// An operating system error has been detected, like a failed fork
+	EX_OSERR = 71
+	// A system file does not exist, cannot be opened, or has some sort of error
+	EX_OSFILE = 72
+	// A user specified output file cannot be created
+	EX_CANTCREAT = 73
+	// An error occurred while doing I/O on some file
+	EX_IOERR = 74
+	// Temporary failure, indicating something that is not really an error, the user is invited to retry
+	EX_TEMPFAIL = 75
+	// The remote system returned something that was not possible during a protocol exchange
+	EX_PROTOCOL = 76
+	// Insufficient permission to perform the operation
+	EX_NOPERM = 77
+	// Something was found in an unconfigured or misconfigured state
+	EX_CONFIG = 78
+)
+
+func newTemplate(errorType string, exitCode int, message string) *khata.KhataTemplate {
+	return khata.NewTemplate().
+		SetType(errorType).
+		SetExitCode(exitCode).
+		SetMessage(message)
+}
+
+// Templates of the errors exiting with each code of sysexits.h
+var (
+	UsageError    = newTemplate("UsageError", EX_USAGE, "command line usage error")
+	DataError     = newTemplate("DataError", EX_DATAERR, "data format error")
+	NoInput       = newTemplate("NoInput", EX_NOINPUT, "cannot open input")
+	NoUser        = newTemplate("NoUser", EX_NOUSER, "addressee unknown")
+	NoHost        = newTemplate("NoHost", EX_NOHOST, "host name unknown")
+	Unavailable   = newTemplate("Unavailable", EX_UNAVAILABLE, "service unavailable")
+	SoftwareError = newTemplate("SoftwareError", EX_SOFTWARE, "internal software error")
+	OSError       = newTemplate("OSError", EX_OSERR, "system error")
+	OSFileError   = newTemplate("OSFileError", EX_OSFILE, "critical OS file missing")
+	CantCreate    = newTemplate("CantCreate", EX_CANTCREAT, "cannot create output file")
+	IOError       = newTemplate("IOError", EX_IOERR, "input/output error")
+	TempFail      = newTemplate("TempFail", EX_TEMPFAIL, "temporary failure")
+	ProtocolError = newTemplate("ProtocolError", EX_PROTOCOL, "remote error in protocol")
+	NoPermission  = newTemplate("NoPermission", EX_NOPERM, "permission denied")
+	ConfigError   = newTemplate("ConfigError", EX_CONFIG, "configuration error")
+)
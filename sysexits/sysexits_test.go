@@ -0,0 +1,21 @@
+package sysexits_test
+
+import (
+	"testing"
+
+	"github.com/cmseguin/khata/sysexits"
+)
+
+func TestTemplates(t *testing.T) {
+	k := sysexits.UsageError.Extend().SetCode(1).New("expected one file")
+
+	if k.ExitStatus() != sysexits.EX_USAGE || k.Type() != "UsageError" || !k.IsFatal() {
+		t.Error("UsageError did not create fatal errors exiting with EX_USAGE")
+		return
+	}
+
+	if sysexits.ConfigError.New().Error() != "configuration error" || sysexits.TempFail.ExitCode() != sysexits.EX_TEMPFAIL {
+		t.Error("The templates did not set the message and the exit code of sysexits.h")
+		return
+	}
+}
@@ -0,0 +1,151 @@
+package khata_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/cmseguin/khata"
+)
+
+func TestTemplateInheritsParentChanges(t *testing.T) {
+	root := khata.NewTemplate().SetType("HTTP").SetNonFatal()
+	child := root.Extend().SetCode(404)
+	grandChild := child.Extend().SetMessage("not found")
+
+	root.SetType("HTTPError")
+
+	if grandChild.Type() != "HTTPError" {
+		t.Error("Type() did not resolve the type through the parents")
+		return
+	}
+
+	if grandChild.Code() != 404 {
+		t.Error("Code() did not resolve the code through the parent")
+		return
+	}
+
+	child.SetType("NotFound")
+
+	if grandChild.Type() != "NotFound" || root.Type() != "HTTPError" {
+		t.Error("Type() did not resolve the type from the closest parent")
+		return
+	}
+
+	grandChild.SetType("Local")
+	child.SetType("Changed")
+
+	if grandChild.Type() != "Local" {
+		t.Error("SetType() on a parent changed an overridden type")
+		return
+	}
+
+	k := grandChild.New()
+
+	if k.Error() != "not found" || k.Code() != 404 || !k.IsNonFatal() {
+		t.Error("New() did not use the resolved fields")
+		return
+	}
+}
+
+func TestTemplateIsOverridden(t *testing.T) {
+	root := khata.NewTemplate()
+	child := root.Extend().SetCode(500)
+
+	if !root.IsOverridden("type") {
+		t.Error("IsOverridden() returned false for a field of a root template")
+		return
+	}
+
+	if !child.IsOverridden("code") || child.IsOverridden("type") || child.IsOverridden("unknown") {
+		t.Error("IsOverridden() did not report the local fields")
+		return
+	}
+}
+
+func TestTemplatePropertiesInheritance(t *testing.T) {
+	root := khata.NewTemplate().SetProperty("service", "api")
+	child := root.Extend()
+	grandChild := child.Extend()
+
+	root.SetProperty("region", "eu")
+
+	if grandChild.GetProperty("region") != "eu" {
+		t.Error("GetProperty() did not resolve a property added to the root after extension")
+		return
+	}
+
+	child.RemoveProperty("service")
+
+	if grandChild.HasProperty("service") || !root.HasProperty("service") {
+		t.Error("RemoveProperty() did not hide the inherited property only from the child")
+		return
+	}
+
+	grandChild.SetProperty("service", "worker")
+
+	if !grandChild.IsPropertyOverridden("service") || child.IsPropertyOverridden("region") {
+		t.Error("IsPropertyOverridden() did not report the local properties")
+		return
+	}
+
+	keys := grandChild.PropertiesKeys()
+
+	if len(keys) != 2 || keys[0] != "region" || keys[1] != "service" {
+		t.Error("PropertiesKeys() did not return the resolved keys")
+		return
+	}
+
+	k := grandChild.New()
+	k.SetProperty("extra", true)
+
+	if grandChild.HasProperty("extra") || k.GetProperty("service") != "worker" {
+		t.Error("New() did not copy the resolved properties")
+		return
+	}
+}
+
+func TestTemplateIntrospection(t *testing.T) {
+	root := khata.NewTemplate()
+	child := root.Extend()
+	child2 := root.Extend()
+	grandChild := child.Extend()
+
+	if grandChild.Parent() != child || root.Parent() != nil {
+		t.Error("Parent() did not return the parent template")
+		return
+	}
+
+	ancestors := grandChild.Ancestors()
+
+	if len(ancestors) != 2 || ancestors[0] != child || ancestors[1] != root {
+		t.Error("Ancestors() did not return the parents from the closest to the root")
+		return
+	}
+
+	children := root.Children()
+
+	if len(children) != 2 || children[0] != child || children[1] != child2 {
+		t.Error("Children() did not return the direct children")
+		return
+	}
+}
+
+func TestTemplateExtendConcurrently(t *testing.T) {
+	root := khata.NewTemplate()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			root.Extend()
+			root.Children()
+		}()
+	}
+	wg.Wait()
+
+	if len(root.Children()) != 10 {
+		t.Error("Extend() lost children extended concurrently")
+		return
+	}
+}